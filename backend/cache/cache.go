@@ -54,6 +54,56 @@ func (r *RedisClient) Get(ctx context.Context, key string) ([]byte, error) {
 	return val, err
 }
 
+// GetDel retrieves a value and removes it, so it can be read only once
+func (r *RedisClient) GetDel(ctx context.Context, key string) ([]byte, error) {
+	val, err := r.Client.GetDel(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return val, err
+}
+
+// Incr increments a counter and returns its new value. The counter expires
+// ttl after its last increment.
+func (r *RedisClient) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := r.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// Hit counts a hit on a fixed-window counter and returns the count and the
+// time left in the window. The window starts at the first hit.
+func (r *RedisClient) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	var incr *redis.IntCmd
+	var ttl *redis.DurationCmd
+	_, err := r.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, window)
+		ttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return incr.Val(), ttl.Val(), nil
+}
+
+// TTL returns the time left before a key expires, or 0 when it doesn't exist
+func (r *RedisClient) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.Client.PTTL(ctx, key).Result()
+	if err != nil || ttl < 0 {
+		return 0, err
+	}
+	return ttl, nil
+}
+
 // Delete removes a key
 func (r *RedisClient) Delete(ctx context.Context, key string) error {
 	return r.Client.Del(ctx, key).Err()
@@ -81,7 +131,53 @@ func CacheKey(projectSlug, langCode, format string) string {
 	return fmt.Sprintf("translations:%s:%s:%s", projectSlug, langCode, format)
 }
 
+// ExportCacheKey generates a cache key for a variant of a translation export,
+// e.g. parts "env:<id>" or "fallback" for environment or fallback exports
+func ExportCacheKey(projectSlug, langCode, format string, parts ...string) string {
+	key := CacheKey(projectSlug, langCode, format)
+	for _, part := range parts {
+		key += ":" + part
+	}
+	return key
+}
+
 // ProjectCachePattern returns a pattern matching all cache keys for a project
 func ProjectCachePattern(projectSlug string) string {
 	return fmt.Sprintf("translations:%s:*", projectSlug)
 }
+
+// OIDCStateKey holds a pending single sign-on attempt by its state parameter
+func OIDCStateKey(state string) string {
+	return fmt.Sprintf("oidc:state:%s", state)
+}
+
+// OIDCLoginCodeKey holds a completed single sign-on login until the frontend
+// exchanges its one-time code for tokens
+func OIDCLoginCodeKey(code string) string {
+	return fmt.Sprintf("oidc:code:%s", code)
+}
+
+// MFAAttemptsKey counts the codes tried with an "mfa pending" token
+func MFAAttemptsKey(tokenID string) string {
+	return fmt.Sprintf("mfa:attempts:%s", tokenID)
+}
+
+// MFAUsedKey marks an "mfa pending" token that completed its login
+func MFAUsedKey(tokenID string) string {
+	return fmt.Sprintf("mfa:used:%s", tokenID)
+}
+
+// RateLimitKey counts the requests of a client for a rate limiter
+func RateLimitKey(limiter, client string) string {
+	return fmt.Sprintf("ratelimit:%s:%s", limiter, client)
+}
+
+// BackoffFailuresKey counts the recent failures of a client for a limiter
+func BackoffFailuresKey(limiter, client string) string {
+	return fmt.Sprintf("backoff:%s:failures:%s", limiter, client)
+}
+
+// BackoffLockKey marks a client locked out by a limiter
+func BackoffLockKey(limiter, client string) string {
+	return fmt.Sprintf("backoff:%s:lock:%s", limiter, client)
+}
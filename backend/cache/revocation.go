@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RevokedSessionKey marks a login session whose access tokens are rejected
+func RevokedSessionKey(sessionID string) string {
+	return fmt.Sprintf("revoked:session:%s", sessionID)
+}
+
+// RevokedUserKey holds the time before which a user's access tokens are rejected
+func RevokedUserKey(userID string) string {
+	return fmt.Sprintf("revoked:user:%s", userID)
+}
+
+// RevokeSession rejects the access tokens of a session. ttl should cover the
+// access token lifetime; the entry is useless once they have expired.
+func (r *RedisClient) RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	return r.Client.Set(ctx, RevokedSessionKey(sessionID), "1", ttl).Err()
+}
+
+// RevokeUserTokens rejects every access token issued to a user until now
+func (r *RedisClient) RevokeUserTokens(ctx context.Context, userID string, ttl time.Duration) error {
+	return r.Client.Set(ctx, RevokedUserKey(userID), strconv.FormatInt(time.Now().Unix(), 10), ttl).Err()
+}
+
+// IsTokenRevoked reports whether an access token of the session, issued to
+// the user at issuedAt, has been revoked
+func (r *RedisClient) IsTokenRevoked(ctx context.Context, userID, sessionID string, issuedAt time.Time) (bool, error) {
+	vals, err := r.Client.MGet(ctx, RevokedSessionKey(sessionID), RevokedUserKey(userID)).Result()
+	if err != nil {
+		return false, err
+	}
+	if sessionID != "" && vals[0] != nil {
+		return true, nil
+	}
+	if cutoff, ok := vals[1].(string); ok {
+		revokedAt, err := strconv.ParseInt(cutoff, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		// Token times have second precision, so a token from the same second
+		// as the revocation is rejected too
+		return issuedAt.Unix() <= revokedAt, nil
+	}
+	return false, nil
+}
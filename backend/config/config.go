@@ -1,30 +1,65 @@
 package config
 
-import "os"
+import (
+	"os"
+	"time"
+)
 
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	RedisHost  string
-	RedisPort  string
-	JWTSecret  string
-	Port       string
+	DBHost          string
+	DBPort          string
+	DBUser          string
+	DBPassword      string
+	DBName          string
+	RedisHost       string
+	RedisPort       string
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	SMTPHost        string
+	SMTPPort        string
+	SMTPUsername    string
+	SMTPPassword    string
+	MailFrom        string
+	AppURL          string
+	// Single sign-on through an OpenID Connect provider; enabled when
+	// OIDCIssuer and OIDCClientID are set
+	OIDCIssuer           string
+	OIDCClientID         string
+	OIDCClientSecret     string
+	OIDCRedirectURL      string
+	OIDCScopes           string
+	OIDCProviderName     string
+	DisablePasswordLogin bool
+	Port                 string
 }
 
 func Load() *Config {
 	return &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBName:     getEnv("DB_NAME", "translate_management"),
-		RedisHost:  getEnv("REDIS_HOST", "localhost"),
-		RedisPort:  getEnv("REDIS_PORT", "6379"),
-		JWTSecret:  getEnv("JWT_SECRET", "dev-secret-key"),
-		Port:       getEnv("PORT", "3000"),
+		DBHost:               getEnv("DB_HOST", "localhost"),
+		DBPort:               getEnv("DB_PORT", "5432"),
+		DBUser:               getEnv("DB_USER", "postgres"),
+		DBPassword:           getEnv("DB_PASSWORD", "postgres"),
+		DBName:               getEnv("DB_NAME", "translate_management"),
+		RedisHost:            getEnv("REDIS_HOST", "localhost"),
+		RedisPort:            getEnv("REDIS_PORT", "6379"),
+		JWTSecret:            getEnv("JWT_SECRET", "dev-secret-key"),
+		AccessTokenTTL:       getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:      getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		SMTPHost:             getEnv("SMTP_HOST", ""),
+		SMTPPort:             getEnv("SMTP_PORT", "587"),
+		SMTPUsername:         getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:         getEnv("SMTP_PASSWORD", ""),
+		MailFrom:             getEnv("MAIL_FROM", "Translate Management <no-reply@localhost>"),
+		AppURL:               getEnv("APP_URL", "http://localhost:5173"),
+		OIDCIssuer:           getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:         getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:     getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:      getEnv("OIDC_REDIRECT_URL", "http://localhost:3000/api/auth/oidc/callback"),
+		OIDCScopes:           getEnv("OIDC_SCOPES", "openid email profile"),
+		OIDCProviderName:     getEnv("OIDC_PROVIDER_NAME", "SSO"),
+		DisablePasswordLogin: getEnv("DISABLE_PASSWORD_LOGIN", "false") == "true",
+		Port:                 getEnv("PORT", "3000"),
 	}
 }
 
@@ -34,3 +69,12 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getDuration reads a duration such as "15m" or "720h", falling back when
+// the variable is unset or invalid
+func getDuration(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(getEnv(key, "")); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
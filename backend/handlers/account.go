@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"translate-management/mailer"
+	"translate-management/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	verifyEmailTokenTTL   = 24 * time.Hour
+	resetPasswordTokenTTL = time.Hour
+)
+
+// createUserToken stores a single-use token for purpose ("verify_email" or
+// "reset_password") and returns it. Earlier unused tokens of the user for the
+// same purpose stop working, so only the latest link is valid.
+func (h *AuthHandler) createUserToken(userID, purpose string, ttl time.Duration) (string, error) {
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(context.Background())
+
+	if _, err := tx.Exec(context.Background(),
+		`DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`, userID, purpose,
+	); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(context.Background(),
+		`INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)`,
+		userID, purpose, hashToken(token), time.Now().Add(ttl),
+	); err != nil {
+		return "", err
+	}
+	return token, tx.Commit(context.Background())
+}
+
+// consumeUserToken marks a valid token as used and returns its user.
+// It returns pgx.ErrNoRows for unknown, used or expired tokens.
+func consumeUserToken(tx pgx.Tx, token, purpose string) (string, error) {
+	var userID string
+	err := tx.QueryRow(context.Background(),
+		`UPDATE user_tokens SET used_at = NOW()
+		 WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		 RETURNING user_id`,
+		hashToken(token), purpose,
+	).Scan(&userID)
+	return userID, err
+}
+
+// appLink builds a link to a frontend page carrying a token
+func (h *AuthHandler) appLink(path, token string) string {
+	return h.appURL(path) + "?token=" + url.QueryEscape(token)
+}
+
+func (h *AuthHandler) sendMail(msg mailer.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	return h.Mailer.Send(ctx, msg)
+}
+
+// sendVerificationEmail mails the user a link to confirm their address
+func (h *AuthHandler) sendVerificationEmail(user models.User) error {
+	token, err := h.createUserToken(user.ID, "verify_email", verifyEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+	return h.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in 24 hours. If you didn't create an account, you can ignore this email.\n",
+			user.Name, h.appLink("/verify-email", token)),
+	})
+}
+
+// VerifyEmail confirms the user's email address with the token from the
+// verification email
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req models.VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token is required"})
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	userID, err := consumeUserToken(tx, req.Token, "verify_email")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired token"})
+	}
+	if _, err := tx.Exec(context.Background(),
+		`UPDATE users SET email_verified = TRUE, updated_at = NOW() WHERE id = $1`, userID,
+	); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify email"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Email verified"})
+}
+
+// ResendVerification mails a new verification link to the current user
+func (h *AuthHandler) ResendVerification(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var user models.User
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT id, email, name, email_verified FROM users WHERE id = $1`, userID,
+	).Scan(&user.ID, &user.Email, &user.Name, &user.EmailVerified)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.EmailVerified {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email is already verified"})
+	}
+
+	if err := h.sendVerificationEmail(user); err != nil {
+		log.Printf("Error sending verification email: %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Failed to send verification email"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Verification email sent"})
+}
+
+// ForgotPassword mails a password reset link. The response is the same
+// whether or not the address belongs to an account, and the email is sent in
+// the background so the response time doesn't tell either.
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req models.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email is required"})
+	}
+
+	var user models.User
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT id, email, name FROM users WHERE email = $1`, req.Email,
+	).Scan(&user.ID, &user.Email, &user.Name)
+	if err == nil {
+		go func() {
+			if err := h.sendPasswordResetEmail(user); err != nil {
+				log.Printf("Error sending password reset email: %v", err)
+			}
+		}()
+	}
+
+	return c.JSON(fiber.Map{"message": "If an account uses this email, a password reset link has been sent"})
+}
+
+func (h *AuthHandler) sendPasswordResetEmail(user models.User) error {
+	token, err := h.createUserToken(user.ID, "reset_password", resetPasswordTokenTTL)
+	if err != nil {
+		return err
+	}
+	return h.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nChoose a new password by opening this link:\n\n%s\n\n"+
+			"The link expires in 1 hour and works once. If you didn't ask to reset your password, you can ignore this email.\n",
+			user.Name, h.appLink("/reset-password", token)),
+	})
+}
+
+// ResetPassword sets a new password with the token from the reset email and
+// signs the user out everywhere. Opening the emailed link also proves the
+// address, so it is marked verified.
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req models.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token is required"})
+	}
+	if len(req.Password) < 6 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password must be at least 6 characters"})
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	userID, err := consumeUserToken(tx, req.Token, "reset_password")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired token"})
+	}
+	if _, err := tx.Exec(context.Background(),
+		`UPDATE users SET password_hash = $2, email_verified = TRUE, updated_at = NOW() WHERE id = $1`,
+		userID, string(hash),
+	); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update password"})
+	}
+	if _, err := tx.Exec(context.Background(),
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID,
+	); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke sessions"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
+
+	if err := h.Cache.RevokeUserTokens(context.Background(), userID, h.Cfg.AccessTokenTTL); err != nil {
+		log.Printf("Error revoking access tokens: %v", err)
+	}
+
+	return c.JSON(fiber.Map{"message": "Password has been reset"})
+}
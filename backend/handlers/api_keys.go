@@ -6,13 +6,35 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
 
 	"translate-management/models"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// apiKeyScopes are the scopes an API key can be granted: "read" for the
+// export API, "write" to push keys and source strings and "import" to import
+// translations through the CI API
+var apiKeyScopes = []string{"read", "write", "import"}
+
+// apiKeyColumns selects an API key without its secrets
+const apiKeyColumns = `id, project_id, name, key_prefix, scopes, env_id, is_active, expires_at,
+	allowed_cidrs, previous_key_expires_at, rotated_at, last_used_at, created_at`
+
+// scanAPIKey reads a row selected with apiKeyColumns
+func scanAPIKey(row pgx.Row) (models.APIKey, error) {
+	var k models.APIKey
+	err := row.Scan(&k.ID, &k.ProjectID, &k.Name, &k.KeyPrefix, &k.Scopes, &k.EnvID, &k.IsActive, &k.ExpiresAt,
+		&k.AllowedCIDRs, &k.PreviousExpiresAt, &k.RotatedAt, &k.LastUsedAt, &k.CreatedAt)
+	return k, err
+}
+
 type APIKeyHandler struct {
 	DB *pgxpool.Pool
 }
@@ -39,8 +61,7 @@ func (h *APIKeyHandler) List(c *fiber.Ctx) error {
 	}
 
 	rows, err := h.DB.Query(context.Background(),
-		`SELECT id, project_id, name, key_prefix, scopes, is_active, last_used_at, created_at 
-		 FROM api_keys WHERE project_id = $1 ORDER BY created_at DESC`,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE project_id = $1 ORDER BY created_at DESC`,
 		projectID,
 	)
 	if err != nil {
@@ -50,8 +71,8 @@ func (h *APIKeyHandler) List(c *fiber.Ctx) error {
 
 	keys := []models.APIKey{}
 	for rows.Next() {
-		var k models.APIKey
-		if err := rows.Scan(&k.ID, &k.ProjectID, &k.Name, &k.KeyPrefix, &k.Scopes, &k.IsActive, &k.LastUsedAt, &k.CreatedAt); err != nil {
+		k, err := scanAPIKey(rows)
+		if err != nil {
 			continue
 		}
 		keys = append(keys, k)
@@ -89,25 +110,46 @@ func (h *APIKeyHandler) Create(c *fiber.Ctx) error {
 	if len(req.Scopes) == 0 {
 		req.Scopes = []string{"read"}
 	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Scopes must be read, write or import"})
+		}
+	}
+	slices.Sort(req.Scopes)
+	req.Scopes = slices.Compact(req.Scopes)
+
+	var envID *string
+	if req.EnvID != "" {
+		var envExists bool
+		if err := h.DB.QueryRow(context.Background(),
+			`SELECT EXISTS(SELECT 1 FROM environments WHERE id = $1 AND project_id = $2)`, req.EnvID, projectID,
+		).Scan(&envExists); err != nil || !envExists {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Environment not found"})
+		}
+		envID = &req.EnvID
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Expiry must be in the future"})
+	}
+
+	allowedCIDRs, err := normalizeCIDRs(req.AllowedCIDRs)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	// Generate random API key
-	rawKey, err := generateAPIKey()
+	rawKey, keyHash, err := generateAPIKey()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate key"})
 	}
 
-	// Hash for storage
-	hash := sha256.Sum256([]byte(rawKey))
-	keyHash := fmt.Sprintf("%x", hash)
-	keyPrefix := rawKey[:8]
-
-	var k models.APIKey
-	err = h.DB.QueryRow(context.Background(),
-		`INSERT INTO api_keys (project_id, name, key_hash, key_prefix, scopes) 
-		 VALUES ($1, $2, $3, $4, $5) 
-		 RETURNING id, project_id, name, key_prefix, scopes, is_active, last_used_at, created_at`,
-		projectID, req.Name, keyHash, keyPrefix, req.Scopes,
-	).Scan(&k.ID, &k.ProjectID, &k.Name, &k.KeyPrefix, &k.Scopes, &k.IsActive, &k.LastUsedAt, &k.CreatedAt)
+	k, err := scanAPIKey(h.DB.QueryRow(context.Background(),
+		`INSERT INTO api_keys (project_id, name, key_hash, key_prefix, scopes, env_id, expires_at, allowed_cidrs) 
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
+		 RETURNING `+apiKeyColumns,
+		projectID, req.Name, keyHash, rawKey[:8], req.Scopes, envID, req.ExpiresAt, allowedCIDRs,
+	))
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create API key"})
@@ -152,10 +194,236 @@ func (h *APIKeyHandler) Delete(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "API key deactivated"})
 }
 
-func generateAPIKey() (string, error) {
+// Update changes an API key's expiry and IP allowlist, e.g. to extend a key
+// about to expire or add a new CI runner's address without a new secret
+func (h *APIKeyHandler) Update(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	// Verify project ownership (either creator or member with 'owner' role)
+	var exists bool
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM projects p 
+			LEFT JOIN project_members pm ON p.id = pm.project_id AND pm.user_id = $2
+			WHERE p.id = $1 AND (p.created_by = $2 OR pm.role = 'owner')
+		)`
+	if err := h.DB.QueryRow(context.Background(), query, projectID, userID).Scan(&exists); err != nil || !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or owner access required"})
+	}
+	keyID := c.Params("keyId")
+
+	var req models.UpdateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Expiry must be in the future"})
+	}
+	setExpiry := req.ExpiresAt != nil || req.ClearExpiry
+
+	var allowedCIDRs []string
+	if req.AllowedCIDRs != nil {
+		var err error
+		if allowedCIDRs, err = normalizeCIDRs(*req.AllowedCIDRs); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	k, err := scanAPIKey(h.DB.QueryRow(context.Background(),
+		`UPDATE api_keys SET
+		 	expires_at = CASE WHEN $3 THEN $4::timestamptz ELSE expires_at END,
+		 	allowed_cidrs = COALESCE($5::text[], allowed_cidrs)
+		 WHERE id = $1 AND project_id = $2
+		 RETURNING `+apiKeyColumns,
+		keyID, projectID, setExpiry, req.ExpiresAt, allowedCIDRs,
+	))
+	if err == pgx.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "API key not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update API key"})
+	}
+
+	return c.JSON(k)
+}
+
+// Rotate replaces an API key's secret. The old secret keeps working for
+// overlap_minutes (default 60) so clients can switch over; rotating again
+// within that window ends it at once.
+func (h *APIKeyHandler) Rotate(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	// Verify project ownership (either creator or member with 'owner' role)
+	var exists bool
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM projects p 
+			LEFT JOIN project_members pm ON p.id = pm.project_id AND pm.user_id = $2
+			WHERE p.id = $1 AND (p.created_by = $2 OR pm.role = 'owner')
+		)`
+	if err := h.DB.QueryRow(context.Background(), query, projectID, userID).Scan(&exists); err != nil || !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or owner access required"})
+	}
+	keyID := c.Params("keyId")
+
+	var req models.RotateAPIKeyRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	overlap := 60
+	if req.OverlapMinutes != nil {
+		overlap = *req.OverlapMinutes
+	}
+	if overlap < 0 || overlap > maxRotationOverlapMinutes {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "overlap_minutes must be between 0 and 10080 (7 days)"})
+	}
+
+	rawKey, keyHash, err := generateAPIKey()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate key"})
+	}
+
+	k, err := scanAPIKey(h.DB.QueryRow(context.Background(),
+		`UPDATE api_keys SET
+		 	previous_key_hash = key_hash,
+		 	previous_key_expires_at = NOW() + make_interval(mins => $3),
+		 	key_hash = $4, key_prefix = $5, rotated_at = NOW()
+		 WHERE id = $1 AND project_id = $2 AND is_active = TRUE
+		 RETURNING `+apiKeyColumns,
+		keyID, projectID, overlap, keyHash, rawKey[:8],
+	))
+	if err == pgx.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Active API key not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rotate API key"})
+	}
+
+	return c.JSON(models.CreateAPIKeyResponse{
+		APIKey: k,
+		RawKey: rawKey,
+	})
+}
+
+// Usage returns an API key's hourly request counts and its exports per
+// language and format over the last ?hours= (default 168, at most 2160).
+// Counts are written in batches, so the last few seconds may be missing.
+func (h *APIKeyHandler) Usage(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	// Verify project ownership (either creator or member with 'owner' role)
+	var exists bool
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM projects p 
+			LEFT JOIN project_members pm ON p.id = pm.project_id AND pm.user_id = $2
+			WHERE p.id = $1 AND (p.created_by = $2 OR pm.role = 'owner')
+		)`
+	if err := h.DB.QueryRow(context.Background(), query, projectID, userID).Scan(&exists); err != nil || !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or owner access required"})
+	}
+	keyID := c.Params("keyId")
+
+	hours := c.QueryInt("hours", 168)
+	if hours < 1 || hours > 2160 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "hours must be between 1 and 2160"})
+	}
+
+	usage := models.APIKeyUsage{
+		KeyID:   keyID,
+		From:    time.Now().Truncate(time.Hour).Add(-time.Duration(hours-1) * time.Hour),
+		Hours:   []models.APIKeyUsageHour{},
+		Exports: []models.APIKeyExportUsage{},
+	}
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT last_used_at FROM api_keys WHERE id = $1 AND project_id = $2`, keyID, projectID,
+	).Scan(&usage.LastUsedAt)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "API key not found"})
+	}
+
+	rows, err := h.DB.Query(context.Background(),
+		`SELECT hour, SUM(requests), SUM(errors), SUM(cache_hits), SUM(cache_misses), SUM(old_secret_requests)
+		 FROM api_key_usage WHERE api_key_id = $1 AND hour >= $2
+		 GROUP BY hour ORDER BY hour`,
+		keyID, usage.From,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch usage"})
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u models.APIKeyUsageHour
+		if err := rows.Scan(&u.Hour, &u.Requests, &u.Errors, &u.CacheHits, &u.CacheMisses, &u.OldSecretRequests); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch usage"})
+		}
+		usage.Hours = append(usage.Hours, u)
+		usage.Totals.Requests += u.Requests
+		usage.Totals.Errors += u.Errors
+		usage.Totals.CacheHits += u.CacheHits
+		usage.Totals.CacheMisses += u.CacheMisses
+		usage.Totals.OldSecretRequests += u.OldSecretRequests
+	}
+
+	rows, err = h.DB.Query(context.Background(),
+		`SELECT language_code, format, SUM(requests), SUM(cache_hits), SUM(cache_misses)
+		 FROM api_key_usage WHERE api_key_id = $1 AND hour >= $2 AND language_code <> ''
+		 GROUP BY language_code, format ORDER BY SUM(requests) DESC, language_code, format`,
+		keyID, usage.From,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch usage"})
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e models.APIKeyExportUsage
+		if err := rows.Scan(&e.LanguageCode, &e.Format, &e.Requests, &e.CacheHits, &e.CacheMisses); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch usage"})
+		}
+		usage.Exports = append(usage.Exports, e)
+	}
+
+	return c.JSON(usage)
+}
+
+// maxRotationOverlapMinutes caps how long a rotated secret can keep working
+const maxRotationOverlapMinutes = 7 * 24 * 60
+
+// normalizeCIDRs validates an allowlist. Plain addresses become single-host
+// ranges, and host bits are cleared, so "10.1.2.3/8" is stored as "10.0.0.0/8".
+func normalizeCIDRs(entries []string) ([]string, error) {
+	cidrs := []string{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				return nil, fmt.Errorf("Invalid IP range %q", entry)
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		cidrs = append(cidrs, prefix.Masked().String())
+	}
+	slices.Sort(cidrs)
+	return slices.Compact(cidrs), nil
+}
+
+// generateAPIKey returns a new raw key and the hash stored for it
+func generateAPIKey() (string, string, error) {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
-		return "", err
+		return "", "", err
 	}
-	return "tm_" + hex.EncodeToString(bytes), nil
+	rawKey := "tm_" + hex.EncodeToString(bytes)
+	return rawKey, fmt.Sprintf("%x", sha256.Sum256([]byte(rawKey))), nil
 }
@@ -2,27 +2,114 @@ package handlers
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
 
+	"translate-management/cache"
 	"translate-management/config"
+	"translate-management/mailer"
 	"translate-management/middleware"
 	"translate-management/models"
+	"translate-management/oidc"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthHandler struct {
-	DB  *pgxpool.Pool
-	Cfg *config.Config
+	DB     *pgxpool.Pool
+	Cache  *cache.RedisClient
+	Mailer mailer.Mailer
+	OIDC   *oidc.Provider // nil when single sign-on is not configured
+	Cfg    *config.Config
 }
 
-func NewAuthHandler(db *pgxpool.Pool, cfg *config.Config) *AuthHandler {
-	return &AuthHandler{DB: db, Cfg: cfg}
+func NewAuthHandler(db *pgxpool.Pool, rdb *cache.RedisClient, m mailer.Mailer, provider *oidc.Provider, cfg *config.Config) *AuthHandler {
+	return &AuthHandler{DB: db, Cache: rdb, Mailer: m, OIDC: provider, Cfg: cfg}
+}
+
+func generateOpaqueToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func hashToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
+
+// issueTokens stores a new refresh token in the session familyID (a new
+// session when empty) and signs an access token for it. mfa records that the
+// session was started with a second factor. It returns the response and the
+// id of the stored refresh token.
+func (h *AuthHandler) issueTokens(tx pgx.Tx, user models.User, familyID string, mfa bool) (models.AuthResponse, string, error) {
+	refreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return models.AuthResponse{}, "", err
+	}
+
+	var family *string
+	if familyID != "" {
+		family = &familyID
+	}
+	var tokenID string
+	err = tx.QueryRow(context.Background(),
+		`INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, mfa)
+		 VALUES ($1, COALESCE($2::uuid, uuid_generate_v4()), $3, $4, $5)
+		 RETURNING id, family_id`,
+		user.ID, family, hashToken(refreshToken), time.Now().Add(h.Cfg.RefreshTokenTTL), mfa,
+	).Scan(&tokenID, &familyID)
+	if err != nil {
+		return models.AuthResponse{}, "", err
+	}
+
+	token, err := middleware.GenerateToken(user.ID, user.Username, user.Email, familyID, mfa, h.Cfg)
+	if err != nil {
+		return models.AuthResponse{}, "", err
+	}
+
+	return models.AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(h.Cfg.AccessTokenTTL.Seconds()),
+		User:         user,
+	}, tokenID, nil
+}
+
+// startSession issues the tokens of a new login session and writes them as
+// the response
+func (h *AuthHandler) startSession(c *fiber.Ctx, user models.User, status int, mfa bool) error {
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	resp, _, err := h.issueTokens(tx, user, "", mfa)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
+
+	return c.Status(status).JSON(resp)
 }
 
 // Register creates a new user account
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
+	if h.Cfg.DisablePasswordLogin {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Accounts are created through single sign-on"})
+	}
+
 	var req models.RegisterRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
@@ -51,9 +138,9 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	err = h.DB.QueryRow(context.Background(),
 		`INSERT INTO users (email, username, password_hash, name) 
 		 VALUES ($1, $2, $3, $4) 
-		 RETURNING id, email, username, name, avatar_url, created_at, updated_at`,
+		 RETURNING id, email, username, name, avatar_url, email_verified, created_at, updated_at`,
 		req.Email, req.Username, string(hash), name,
-	).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.AvatarURL, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.AvatarURL, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		if err.Error() == `ERROR: duplicate key value violates unique constraint "users_email_key" (SQLSTATE 23505)` ||
@@ -63,19 +150,20 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create user"})
 	}
 
-	token, err := middleware.GenerateToken(user.ID, user.Username, user.Email, h.Cfg)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+	// The account works before it is verified, and the link can be resent
+	if err := h.sendVerificationEmail(user); err != nil {
+		log.Printf("Error sending verification email: %v", err)
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(models.AuthResponse{
-		Token: token,
-		User:  user,
-	})
+	return h.startSession(c, user, fiber.StatusCreated, false)
 }
 
 // Login authenticates a user
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
+	if h.Cfg.DisablePasswordLogin {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Password login is disabled; sign in with single sign-on"})
+	}
+
 	var req models.LoginRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
@@ -87,10 +175,10 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 
 	var user models.User
 	err := h.DB.QueryRow(context.Background(),
-		`SELECT id, email, username, password_hash, name, avatar_url, created_at, updated_at 
+		`SELECT id, email, username, password_hash, name, avatar_url, email_verified, created_at, updated_at 
 		 FROM users WHERE username = $1`,
 		req.Username,
-	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.Name, &user.AvatarURL, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.Name, &user.AvatarURL, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid credentials"})
@@ -100,15 +188,76 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid credentials"})
 	}
 
-	token, err := middleware.GenerateToken(user.ID, user.Username, user.Email, h.Cfg)
+	return h.completeLogin(c, user)
+}
+
+// Refresh exchanges a refresh token for a new access token and refresh token.
+// Each refresh token can be used once; presenting one that was already
+// exchanged revokes its whole session, since the token has likely leaked.
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req models.RefreshTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "refresh_token is required"})
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	var tokenID, familyID string
+	var mfa bool
+	var expiresAt time.Time
+	var revokedAt *time.Time
+	var replacedBy *string
+	var user models.User
+	err = tx.QueryRow(context.Background(),
+		`SELECT rt.id, rt.family_id, rt.mfa, rt.expires_at, rt.revoked_at, rt.replaced_by,
+			u.id, u.email, u.username, u.name, u.avatar_url, u.email_verified, u.created_at, u.updated_at
+		 FROM refresh_tokens rt
+		 JOIN users u ON u.id = rt.user_id
+		 WHERE rt.token_hash = $1
+		 FOR UPDATE OF rt`,
+		hashToken(req.RefreshToken),
+	).Scan(&tokenID, &familyID, &mfa, &expiresAt, &revokedAt, &replacedBy,
+		&user.ID, &user.Email, &user.Username, &user.Name, &user.AvatarURL, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+
+	if revokedAt != nil {
+		if replacedBy != nil {
+			if err := h.revokeSession(tx, user.ID, familyID); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke session"})
+			}
+			if err := tx.Commit(context.Background()); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+			}
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token has been revoked"})
+	}
+	if time.Now().After(expiresAt) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token has expired"})
+	}
+
+	resp, newID, err := h.issueTokens(tx, user, familyID, mfa)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
 	}
+	if _, err := tx.Exec(context.Background(),
+		`UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by = $2 WHERE id = $1`, tokenID, newID,
+	); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rotate refresh token"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
 
-	return c.JSON(models.AuthResponse{
-		Token: token,
-		User:  user,
-	})
+	return c.JSON(resp)
 }
 
 // Me returns the current authenticated user
@@ -117,10 +266,10 @@ func (h *AuthHandler) Me(c *fiber.Ctx) error {
 
 	var user models.User
 	err := h.DB.QueryRow(context.Background(),
-		`SELECT id, email, username, name, avatar_url, created_at, updated_at 
+		`SELECT id, email, username, name, avatar_url, email_verified, created_at, updated_at 
 		 FROM users WHERE id = $1`,
 		userID,
-	).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.AvatarURL, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.AvatarURL, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
@@ -129,7 +278,50 @@ func (h *AuthHandler) Me(c *fiber.Ctx) error {
 	return c.JSON(user)
 }
 
-// Logout is a placeholder (JWT is stateless, client discards token)
+// revokeSession revokes the refresh tokens of a session and rejects its
+// access tokens until they expire
+func (h *AuthHandler) revokeSession(tx pgx.Tx, userID, familyID string) error {
+	if _, err := tx.Exec(context.Background(),
+		`UPDATE refresh_tokens SET revoked_at = NOW()
+		 WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL`,
+		familyID, userID,
+	); err != nil {
+		return err
+	}
+	return h.Cache.RevokeSession(context.Background(), familyID, h.Cfg.AccessTokenTTL)
+}
+
+// Logout ends the current session: its refresh tokens are revoked and its
+// access tokens stop working immediately. With ?all=true every session of
+// the user is ended, e.g. after a lost device or a password leak.
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	sessionID := c.Locals("session_id").(string)
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	if c.QueryBool("all", false) {
+		if _, err := tx.Exec(context.Background(),
+			`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID,
+		); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke sessions"})
+		}
+		if err := h.Cache.RevokeUserTokens(context.Background(), userID, h.Cfg.AccessTokenTTL); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke sessions"})
+		}
+	} else if sessionID != "" {
+		if err := h.revokeSession(tx, userID, sessionID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke session"})
+		}
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
+
 	return c.JSON(fiber.Map{"message": "Logged out successfully"})
 }
@@ -2,14 +2,12 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
 	"time"
 
 	"translate-management/cache"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/vmihailenco/msgpack/v5"
 )
 
 type CacheHandler struct {
@@ -133,7 +131,7 @@ func (h *CacheHandler) Rebuild(c *fiber.Ctx) error {
 	// For each language and format, build the cache
 	// Note: We're doing this synchronously for simplicity, but for large projects it should be async.
 	for _, l := range languages {
-		for _, format := range []string{"json", "msgpack"} {
+		for _, format := range exportFormats {
 			if err := h.rebuildCacheForLanguage(slug, projectID, l.ID, l.Code, format); err != nil {
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rebuild cache for " + l.Code})
 			}
@@ -148,41 +146,12 @@ func (h *CacheHandler) Rebuild(c *fiber.Ctx) error {
 }
 
 func (h *CacheHandler) rebuildCacheForLanguage(slug, projectID, langID, langCode, format string) error {
-	rows, err := h.DB.Query(context.Background(),
-		`SELECT tk.key, t.value
-		 FROM translation_keys tk
-		 LEFT JOIN translations t ON t.key_id = tk.id AND t.language_id = $2
-		 WHERE tk.project_id = $1
-		 ORDER BY tk.key`,
-		projectID, langID,
-	)
+	doc, err := loadExportDocument(h.DB, projectID, slug, langID, langCode, "", false)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
-
-	flatMap := make(map[string]string)
-	for rows.Next() {
-		var key string
-		var value *string
-		if err := rows.Scan(&key, &value); err == nil {
-			if value != nil {
-				flatMap[key] = *value
-			} else {
-				flatMap[key] = ""
-			}
-		}
-	}
-
-	nested := buildNestedMap(flatMap)
-
-	var data []byte
-	if format == "msgpack" {
-		data, err = msgpack.Marshal(nested)
-	} else {
-		data, err = json.MarshalIndent(nested, "", "  ")
-	}
 
+	data, err := encodeExport(format, doc)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"translate-management/cache"
+	"translate-management/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CIHandler serves the write API for CI pipelines under /api/v1/ci/:slug,
+// authenticated with a project API key instead of a user session
+type CIHandler struct {
+	DB      *pgxpool.Pool
+	Cache   *cache.RedisClient
+	imports *ImportHandler
+}
+
+func NewCIHandler(db *pgxpool.Pool, rdb *cache.RedisClient) *CIHandler {
+	return &CIHandler{DB: db, Cache: rdb, imports: NewImportHandler(db)}
+}
+
+// project resolves the :slug of the request to a project id and checks the
+// API key belongs to it. Keys pinned to an environment only serve exports,
+// so they can't change the project's keys or base translations.
+func (h *CIHandler) project(c *fiber.Ctx) (string, error) {
+	var projectID string
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT id FROM projects WHERE slug = $1`, c.Params("slug"),
+	).Scan(&projectID)
+	if err != nil {
+		return "", c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
+	}
+
+	if c.Locals("project_id").(string) != projectID {
+		return "", c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "API key does not belong to this project"})
+	}
+	if c.Locals("api_key_env_id") != nil {
+		return "", c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "API keys pinned to an environment are read-only"})
+	}
+	return projectID, nil
+}
+
+// PushKeys creates the keys a project doesn't have yet. Keys that exist keep
+// their translations; a non-empty description replaces theirs.
+func (h *CIHandler) PushKeys(c *fiber.Ctx) error {
+	projectID, err := h.project(c)
+	if projectID == "" {
+		return err
+	}
+
+	var req models.PushKeysRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if len(req.Keys) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Keys are required"})
+	}
+	for i := range req.Keys {
+		req.Keys[i].Key = strings.TrimSpace(req.Keys[i].Key)
+		if req.Keys[i].Key == "" || len(req.Keys[i].Key) > 500 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Each key needs a name of at most 500 characters"})
+		}
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	created := []string{}
+	existing := 0
+	for _, k := range req.Keys {
+		var inserted bool
+		err := tx.QueryRow(context.Background(),
+			`INSERT INTO translation_keys (project_id, key, description, is_plural)
+			 VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (project_id, key) DO UPDATE SET
+			 	description = CASE WHEN EXCLUDED.description <> '' THEN EXCLUDED.description ELSE translation_keys.description END,
+			 	is_plural = translation_keys.is_plural OR EXCLUDED.is_plural,
+			 	updated_at = NOW()
+			 RETURNING xmax = 0`,
+			projectID, k.Key, k.Description, k.IsPlural,
+		).Scan(&inserted)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to push keys"})
+		}
+		if inserted {
+			created = append(created, k.Key)
+		} else {
+			existing++
+		}
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
+
+	h.invalidateCache(c.Params("slug"))
+
+	return c.JSON(fiber.Map{
+		"created":  created,
+		"existing": existing,
+	})
+}
+
+// UploadSource imports source strings into the project's default language,
+// creating keys that don't exist. The body is the same as for an import;
+// its language code is ignored.
+func (h *CIHandler) UploadSource(c *fiber.Ctx) error {
+	projectID, err := h.project(c)
+	if projectID == "" {
+		return err
+	}
+
+	var req models.ImportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	entries, _, err := parseImportRequest(&req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var langCode string
+	if err := h.DB.QueryRow(context.Background(),
+		`SELECT code FROM languages WHERE project_id = $1 AND is_default = TRUE LIMIT 1`, projectID,
+	).Scan(&langCode); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Project has no default language"})
+	}
+
+	if err := h.imports.importEntries(c, projectID, "", langCode, entries); err != nil {
+		return err
+	}
+	h.invalidateCache(c.Params("slug"))
+	return nil
+}
+
+// Import imports translations like POST /api/projects/:id/import
+func (h *CIHandler) Import(c *fiber.Ctx) error {
+	projectID, err := h.project(c)
+	if projectID == "" {
+		return err
+	}
+
+	var req models.ImportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	entries, langCode, err := parseImportRequest(&req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if langCode == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Language code is required"})
+	}
+
+	if err := h.imports.importEntries(c, projectID, "", langCode, entries); err != nil {
+		return err
+	}
+	h.invalidateCache(c.Params("slug"))
+	return nil
+}
+
+func (h *CIHandler) invalidateCache(slug string) {
+	_ = h.Cache.DeleteByPattern(context.Background(), cache.ProjectCachePattern(slug))
+}
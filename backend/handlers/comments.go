@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"translate-management/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CommentHandler struct {
+	DB *pgxpool.Pool
+}
+
+func NewCommentHandler(db *pgxpool.Pool) *CommentHandler {
+	return &CommentHandler{DB: db}
+}
+
+// commentColumns selects a comment (alias kc) with its author (alias u)
+const commentColumns = `kc.id, kc.key_id, kc.language_id, kc.parent_id, kc.body, kc.created_by,
+	COALESCE(u.username, ''), kc.resolved_by, kc.resolved_at, kc.created_at, kc.updated_at`
+
+// scanComment reads a row selected with commentColumns
+func scanComment(row pgx.Row) (models.KeyComment, error) {
+	var k models.KeyComment
+	err := row.Scan(&k.ID, &k.KeyID, &k.LanguageID, &k.ParentID, &k.Body, &k.CreatedBy,
+		&k.CreatedByName, &k.ResolvedBy, &k.ResolvedAt, &k.CreatedAt, &k.UpdatedAt)
+	return k, err
+}
+
+// verifyKeyAccess checks that the user is a project member and the key belongs
+// to the project, and returns the user's role. Writes a 404 on failure.
+func (h *CommentHandler) verifyKeyAccess(c *fiber.Ctx, projectID, keyID, userID string) (string, error) {
+	var role string
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT
+			CASE
+				WHEN p.created_by = $2 THEN 'owner'
+				ELSE pm.role
+			END as role
+		FROM projects p
+		LEFT JOIN project_members pm ON p.id = pm.project_id AND pm.user_id = $2
+		WHERE p.id = $1 AND (p.created_by = $2 OR pm.user_id = $2)`,
+		projectID, userID).Scan(&role)
+	if err != nil {
+		c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or access denied"})
+		return "", err
+	}
+
+	var exists bool
+	err = h.DB.QueryRow(context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM translation_keys WHERE id = $1 AND project_id = $2)`, keyID, projectID,
+	).Scan(&exists)
+	if err != nil || !exists {
+		c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Key not found"})
+		return "", fiber.NewError(fiber.StatusNotFound, "Key not found")
+	}
+	return role, nil
+}
+
+// canComment reports whether a role may write to comment threads
+func canComment(role string) bool {
+	return role == "owner" || role == "editor" || role == "reviewer"
+}
+
+// List returns the threads of a key with their replies, oldest first.
+// ?lang=xx narrows them to one language (threads about the whole key are
+// always included) and ?resolved=true|false filters by state.
+func (h *CommentHandler) List(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	keyID := c.Params("keyId")
+	userID := c.Locals("user_id").(string)
+
+	if _, err := h.verifyKeyAccess(c, projectID, keyID, userID); err != nil {
+		return err
+	}
+
+	query := `SELECT ` + commentColumns + `
+		 FROM key_comments kc
+		 LEFT JOIN users u ON u.id = kc.created_by
+		 WHERE kc.key_id = $1 AND kc.parent_id IS NULL`
+	args := []interface{}{keyID}
+	if lang := c.Query("lang", ""); lang != "" {
+		args = append(args, lang, projectID)
+		query += ` AND (kc.language_id IS NULL OR kc.language_id = (SELECT id FROM languages WHERE code = $2 AND project_id = $3))`
+	}
+	switch c.Query("resolved", "") {
+	case "true":
+		query += ` AND kc.resolved_at IS NOT NULL`
+	case "false":
+		query += ` AND kc.resolved_at IS NULL`
+	}
+	query += ` ORDER BY kc.created_at ASC`
+
+	rows, err := h.DB.Query(context.Background(), query, args...)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch comments"})
+	}
+	defer rows.Close()
+
+	threads := []models.KeyComment{}
+	index := make(map[string]int)
+	for rows.Next() {
+		k, err := scanComment(rows)
+		if err != nil {
+			continue
+		}
+		index[k.ID] = len(threads)
+		threads = append(threads, k)
+	}
+	if len(threads) == 0 {
+		return c.JSON(threads)
+	}
+
+	threadIDs := make([]string, 0, len(threads))
+	for _, t := range threads {
+		threadIDs = append(threadIDs, t.ID)
+	}
+	replyRows, err := h.DB.Query(context.Background(),
+		`SELECT `+commentColumns+`
+		 FROM key_comments kc
+		 LEFT JOIN users u ON u.id = kc.created_by
+		 WHERE kc.parent_id = ANY($1)
+		 ORDER BY kc.created_at ASC`,
+		threadIDs,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch replies"})
+	}
+	defer replyRows.Close()
+
+	for replyRows.Next() {
+		k, err := scanComment(replyRows)
+		if err != nil || k.ParentID == nil {
+			continue
+		}
+		i := index[*k.ParentID]
+		threads[i].Replies = append(threads[i].Replies, k)
+	}
+
+	return c.JSON(threads)
+}
+
+// Create starts a thread on a key, optionally about one language
+func (h *CommentHandler) Create(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	keyID := c.Params("keyId")
+	userID := c.Locals("user_id").(string)
+
+	role, err := h.verifyKeyAccess(c, projectID, keyID, userID)
+	if err != nil {
+		return err
+	}
+	if !canComment(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	var req models.CreateCommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Comment body is required"})
+	}
+
+	var languageID *string
+	if req.LanguageID != "" {
+		var exists bool
+		if err := h.DB.QueryRow(context.Background(),
+			`SELECT EXISTS(SELECT 1 FROM languages WHERE id = $1 AND project_id = $2)`, req.LanguageID, projectID,
+		).Scan(&exists); err != nil || !exists {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Language not found"})
+		}
+		languageID = &req.LanguageID
+	}
+
+	return h.insert(c, keyID, languageID, nil, req.Body, userID)
+}
+
+// Reply adds a reply to a thread. Replying to a reply adds to its thread.
+func (h *CommentHandler) Reply(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	keyID := c.Params("keyId")
+	commentID := c.Params("commentId")
+	userID := c.Locals("user_id").(string)
+
+	role, err := h.verifyKeyAccess(c, projectID, keyID, userID)
+	if err != nil {
+		return err
+	}
+	if !canComment(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	var req models.CreateCommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Comment body is required"})
+	}
+
+	var threadID string
+	var languageID *string
+	err = h.DB.QueryRow(context.Background(),
+		`SELECT COALESCE(parent_id, id), language_id FROM key_comments WHERE id = $1 AND key_id = $2`,
+		commentID, keyID,
+	).Scan(&threadID, &languageID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Comment not found"})
+	}
+
+	return h.insert(c, keyID, languageID, &threadID, req.Body, userID)
+}
+
+// insert stores a comment and writes it as the 201 response
+func (h *CommentHandler) insert(c *fiber.Ctx, keyID string, languageID, parentID *string, body, userID string) error {
+	k, err := scanComment(h.DB.QueryRow(context.Background(),
+		`WITH kc AS (
+			INSERT INTO key_comments (key_id, language_id, parent_id, body, created_by)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *
+		 )
+		 SELECT `+commentColumns+` FROM kc LEFT JOIN users u ON u.id = kc.created_by`,
+		keyID, languageID, parentID, body, userID,
+	))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create comment"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(k)
+}
+
+// Resolve marks a thread as resolved
+func (h *CommentHandler) Resolve(c *fiber.Ctx) error {
+	return h.setResolved(c, true)
+}
+
+// Reopen marks a resolved thread as open again
+func (h *CommentHandler) Reopen(c *fiber.Ctx) error {
+	return h.setResolved(c, false)
+}
+
+func (h *CommentHandler) setResolved(c *fiber.Ctx, resolved bool) error {
+	projectID := c.Params("id")
+	keyID := c.Params("keyId")
+	commentID := c.Params("commentId")
+	userID := c.Locals("user_id").(string)
+
+	role, err := h.verifyKeyAccess(c, projectID, keyID, userID)
+	if err != nil {
+		return err
+	}
+	if !canComment(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	set := `resolved_at = NULL, resolved_by = NULL`
+	args := []interface{}{commentID, keyID}
+	if resolved {
+		args = append(args, userID)
+		set = fmt.Sprintf(`resolved_at = NOW(), resolved_by = $%d`, len(args))
+	}
+	k, err := scanComment(h.DB.QueryRow(context.Background(),
+		`WITH kc AS (
+			UPDATE key_comments SET `+set+`, updated_at = NOW()
+			WHERE id = $1 AND key_id = $2 AND parent_id IS NULL
+			RETURNING *
+		 )
+		 SELECT `+commentColumns+` FROM kc LEFT JOIN users u ON u.id = kc.created_by`,
+		args...,
+	))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Thread not found"})
+	}
+	return c.JSON(k)
+}
+
+// Delete removes a comment; deleting a thread removes its replies. Authors
+// can delete their own comments and project owners any comment.
+func (h *CommentHandler) Delete(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	keyID := c.Params("keyId")
+	commentID := c.Params("commentId")
+	userID := c.Locals("user_id").(string)
+
+	role, err := h.verifyKeyAccess(c, projectID, keyID, userID)
+	if err != nil {
+		return err
+	}
+
+	var author *string
+	err = h.DB.QueryRow(context.Background(),
+		`SELECT created_by FROM key_comments WHERE id = $1 AND key_id = $2`, commentID, keyID,
+	).Scan(&author)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Comment not found"})
+	}
+	if role != "owner" && (author == nil || *author != userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only the author or a project owner can delete this comment"})
+	}
+
+	if _, err := h.DB.Exec(context.Background(), `DELETE FROM key_comments WHERE id = $1`, commentID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete comment"})
+	}
+	return c.JSON(fiber.Map{"message": "Comment deleted"})
+}
@@ -3,19 +3,23 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"sort"
 
+	"translate-management/cache"
 	"translate-management/models"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type EnvironmentHandler struct {
-	DB *pgxpool.Pool
+	DB    *pgxpool.Pool
+	Cache *cache.RedisClient
 }
 
-func NewEnvironmentHandler(db *pgxpool.Pool) *EnvironmentHandler {
-	return &EnvironmentHandler{DB: db}
+func NewEnvironmentHandler(db *pgxpool.Pool, rdb *cache.RedisClient) *EnvironmentHandler {
+	return &EnvironmentHandler{DB: db, Cache: rdb}
 }
 
 // verifyProjectRole checks project membership and returns the user's role.
@@ -200,3 +204,283 @@ func (h *EnvironmentHandler) Delete(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{"message": "Environment deleted"})
 }
+
+// Promote makes the target environment match this one: keys of the source
+// environment are added to the target, and the target's overrides of those
+// keys are replaced by the source's (or removed where the source has none).
+// With dry_run the diff is returned without applying it.
+func (h *EnvironmentHandler) Promote(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	envID := c.Params("envId")
+	userID := c.Locals("user_id").(string)
+
+	role, err := h.verifyProjectRole(c, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if role != "owner" && role != "editor" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	var req models.PromoteEnvironmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.TargetEnvID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Target environment is required"})
+	}
+	if req.TargetEnvID == envID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot promote an environment into itself"})
+	}
+
+	var found int
+	err = h.DB.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM environments WHERE project_id = $1 AND id IN ($2, $3)`,
+		projectID, envID, req.TargetEnvID,
+	).Scan(&found)
+	if err != nil || found != 2 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Environment not found"})
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	// Serialize promotions into the same target
+	if _, err := tx.Exec(context.Background(),
+		`SELECT id FROM environments WHERE id = $1 FOR UPDATE`, req.TargetEnvID,
+	); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to lock environment"})
+	}
+
+	diff, err := diffEnvironments(tx, envID, req.TargetEnvID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to compare environments"})
+	}
+	diff.DryRun = req.DryRun
+	if !req.Prune {
+		diff.KeysNotPruned, diff.KeysRemoved = diff.KeysRemoved, []string{}
+		diff.Summary["keys_removed"] = 0
+		diff.Summary["keys_not_pruned"] = len(diff.KeysNotPruned)
+	}
+
+	if req.DryRun {
+		return c.JSON(diff)
+	}
+
+	if err := applyPromotion(tx, diff, req.Prune, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to apply promotion"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit promotion"})
+	}
+
+	var slug string
+	_ = h.DB.QueryRow(context.Background(), "SELECT slug FROM projects WHERE id = $1", projectID).Scan(&slug)
+	if slug != "" {
+		_ = h.Cache.DeleteByPattern(context.Background(), cache.ProjectCachePattern(slug))
+	}
+
+	return c.JSON(diff)
+}
+
+// envOverride is a stored environment override, keyed by "<key_id>/<language_id>"
+type envOverride struct {
+	models.PromotedValue
+	Value string
+	Forms string // plural_forms as JSON text, "" when not plural
+}
+
+func loadEnvKeys(tx pgx.Tx, envID string) (map[string]string, error) {
+	rows, err := tx.Query(context.Background(),
+		`SELECT tk.id, tk.key FROM key_environments ke
+		 JOIN translation_keys tk ON tk.id = ke.key_id
+		 WHERE ke.env_id = $1`, envID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[string]string)
+	for rows.Next() {
+		var id, key string
+		if err := rows.Scan(&id, &key); err != nil {
+			return nil, err
+		}
+		keys[id] = key
+	}
+	return keys, rows.Err()
+}
+
+func loadEnvOverrides(tx pgx.Tx, envID string) (map[string]envOverride, error) {
+	rows, err := tx.Query(context.Background(),
+		`SELECT o.key_id, tk.key, o.language_id, l.code, o.value, COALESCE(o.plural_forms::text, '')
+		 FROM translation_overrides o
+		 JOIN translation_keys tk ON tk.id = o.key_id
+		 JOIN languages l ON l.id = o.language_id
+		 WHERE o.env_id = $1`, envID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]envOverride)
+	for rows.Next() {
+		var o envOverride
+		if err := rows.Scan(&o.KeyID, &o.Key, &o.LanguageID, &o.LanguageCode, &o.Value, &o.Forms); err != nil {
+			return nil, err
+		}
+		overrides[o.KeyID+"/"+o.LanguageID] = o
+	}
+	return overrides, rows.Err()
+}
+
+// diffEnvironments computes what promoting source into target changes
+func diffEnvironments(tx pgx.Tx, sourceID, targetID string) (*models.EnvironmentPromotion, error) {
+	sourceKeys, err := loadEnvKeys(tx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	targetKeys, err := loadEnvKeys(tx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	sourceOverrides, err := loadEnvOverrides(tx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	targetOverrides, err := loadEnvOverrides(tx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.EnvironmentPromotion{
+		SourceEnvID:   sourceID,
+		TargetEnvID:   targetID,
+		KeysAdded:     []string{},
+		KeysRemoved:   []string{},
+		ValuesChanged: []models.PromotedValue{},
+	}
+	for id, key := range sourceKeys {
+		if _, ok := targetKeys[id]; !ok {
+			diff.KeysAdded = append(diff.KeysAdded, key)
+		}
+	}
+	for id, key := range targetKeys {
+		if _, ok := sourceKeys[id]; !ok {
+			diff.KeysRemoved = append(diff.KeysRemoved, key)
+		}
+	}
+
+	for cell, src := range sourceOverrides {
+		if _, ok := sourceKeys[src.KeyID]; !ok {
+			continue
+		}
+		dst, exists := targetOverrides[cell]
+		if exists && dst.Value == src.Value && dst.Forms == src.Forms {
+			continue
+		}
+		v := src.PromotedValue
+		v.Action, v.To = "set", src.Value
+		if exists {
+			v.From = dst.Value
+		}
+		diff.ValuesChanged = append(diff.ValuesChanged, v)
+	}
+	for cell, dst := range targetOverrides {
+		if _, ok := sourceKeys[dst.KeyID]; !ok {
+			continue
+		}
+		if _, ok := sourceOverrides[cell]; ok {
+			continue
+		}
+		v := dst.PromotedValue
+		v.Action, v.From = "cleared", dst.Value
+		diff.ValuesChanged = append(diff.ValuesChanged, v)
+	}
+
+	sort.Strings(diff.KeysAdded)
+	sort.Strings(diff.KeysRemoved)
+	sort.Slice(diff.ValuesChanged, func(i, j int) bool {
+		a, b := diff.ValuesChanged[i], diff.ValuesChanged[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.LanguageCode < b.LanguageCode
+	})
+
+	diff.Summary = map[string]int{
+		"keys_added":     len(diff.KeysAdded),
+		"keys_removed":   len(diff.KeysRemoved),
+		"values_set":     0,
+		"values_cleared": 0,
+	}
+	for _, v := range diff.ValuesChanged {
+		diff.Summary["values_"+v.Action]++
+	}
+	return diff, nil
+}
+
+// applyPromotion writes a computed diff to the target environment
+func applyPromotion(tx pgx.Tx, diff *models.EnvironmentPromotion, prune bool, userID string) error {
+	if len(diff.KeysAdded) > 0 {
+		if _, err := tx.Exec(context.Background(),
+			`INSERT INTO key_environments (key_id, env_id)
+			 SELECT ke.key_id, $2 FROM key_environments ke
+			 JOIN translation_keys tk ON tk.id = ke.key_id
+			 WHERE ke.env_id = $1 AND tk.key = ANY($3)
+			 ON CONFLICT DO NOTHING`,
+			diff.SourceEnvID, diff.TargetEnvID, diff.KeysAdded,
+		); err != nil {
+			return err
+		}
+	}
+
+	if prune && len(diff.KeysRemoved) > 0 {
+		if _, err := tx.Exec(context.Background(),
+			`DELETE FROM key_environments ke USING translation_keys tk
+			 WHERE tk.id = ke.key_id AND ke.env_id = $1 AND tk.key = ANY($2)`,
+			diff.TargetEnvID, diff.KeysRemoved,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range diff.ValuesChanged {
+		old, err := readTranslationCell(tx, v.KeyID, v.LanguageID, diff.TargetEnvID)
+		if err != nil {
+			return err
+		}
+		if v.Action == "set" {
+			_, err = tx.Exec(context.Background(),
+				`INSERT INTO translation_overrides (key_id, language_id, env_id, value, plural_forms, updated_by,
+				 	status, approved_value, approved_plural_forms, reviewed_by, reviewed_at)
+				 SELECT key_id, language_id, $4, value, plural_forms, $5,
+				 	status, approved_value, approved_plural_forms, reviewed_by, reviewed_at
+				 FROM translation_overrides
+				 WHERE key_id = $1 AND language_id = $2 AND env_id = $3
+				 ON CONFLICT (key_id, language_id, env_id)
+				 DO UPDATE SET value = EXCLUDED.value, plural_forms = EXCLUDED.plural_forms, updated_at = NOW(), updated_by = EXCLUDED.updated_by,
+				 	status = EXCLUDED.status, approved_value = EXCLUDED.approved_value, approved_plural_forms = EXCLUDED.approved_plural_forms,
+				 	reviewed_by = EXCLUDED.reviewed_by, reviewed_at = EXCLUDED.reviewed_at`,
+				v.KeyID, v.LanguageID, diff.SourceEnvID, diff.TargetEnvID, userID,
+			)
+		} else {
+			_, err = tx.Exec(context.Background(),
+				`DELETE FROM translation_overrides WHERE key_id = $1 AND language_id = $2 AND env_id = $3`,
+				v.KeyID, v.LanguageID, diff.TargetEnvID,
+			)
+		}
+		if err != nil {
+			return err
+		}
+		if err := recordHistory(tx, v.KeyID, v.LanguageID, diff.TargetEnvID, userID, "promote", old, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
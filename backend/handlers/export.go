@@ -4,15 +4,14 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/hex"
-	"encoding/json"
 	"strings"
 	"time"
 
 	"translate-management/cache"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/vmihailenco/msgpack/v5"
 )
 
 type ExportHandler struct {
@@ -24,26 +23,22 @@ func NewExportHandler(db *pgxpool.Pool, rdb *cache.RedisClient) *ExportHandler {
 	return &ExportHandler{DB: db, Cache: rdb}
 }
 
-// Export returns translations for a project/language in JSON or MessagePack format
+// Export returns translations for a project/language in any of the export formats
 func (h *ExportHandler) Export(c *fiber.Ctx) error {
 	slug := c.Params("slug")
 	langCode := c.Params("langCode")
-	format := c.Query("format", "json")
 
-	if format != "json" && format != "msgpack" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format must be 'json' or 'msgpack'"})
+	v, err := h.parseExportVariant(c, slug)
+	if err != nil {
+		return err
 	}
 
-	data, err := h.getOrGenerateData(slug, langCode, format, c)
+	data, err := h.getOrGenerateData(slug, langCode, v, c)
 	if err != nil {
 		return err
 	}
 
-	if format == "msgpack" {
-		c.Set("Content-Type", "application/x-msgpack")
-	} else {
-		c.Set("Content-Type", "application/json")
-	}
+	c.Set("Content-Type", exportContentType(v.Format))
 	// X-Cache header is already set in getOrGenerateData if HIT
 	// But if MISS, we need to set it? getOrGenerateData sets it to MISS on generation.
     
@@ -86,14 +81,14 @@ func buildNestedMap(flatMap map[string]string) map[string]interface{} {
 func (h *ExportHandler) GetVersion(c *fiber.Ctx) error {
 	slug := c.Params("slug")
 	langCode := c.Params("langCode")
-	format := c.Query("format", "json")
 
-	if format != "json" && format != "msgpack" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format must be 'json' or 'msgpack'"})
+	v, err := h.parseExportVariant(c, slug)
+	if err != nil {
+		return err
 	}
 
 	// 1. Try to get from cache
-	cacheKey := cache.CacheKey(slug, langCode, format)
+	cacheKey := v.cacheKey(slug, langCode)
 	cached, err := h.Cache.Get(context.Background(), cacheKey)
 
 	if err != nil || cached == nil {
@@ -109,7 +104,7 @@ func (h *ExportHandler) GetVersion(c *fiber.Ctx) error {
 		// It does: DB queries -> buildNestedMap -> Marshal -> Cache.Set -> Send.
 		
 		// To avoid duplication, let's extract the generation logic.
-		data, err := h.generateExportData(slug, langCode, format, c)
+		data, err := h.generateExportData(slug, langCode, v, c)
 		if err != nil {
 			return err // generateExportData handles error responses
 		}
@@ -124,21 +119,105 @@ func (h *ExportHandler) GetVersion(c *fiber.Ctx) error {
 	})
 }
 
-func (h *ExportHandler) generateExportData(slug, langCode, format string, c *fiber.Ctx) ([]byte, error) {
+func (h *ExportHandler) generateExportData(slug, langCode string, v exportVariant, c *fiber.Ctx) ([]byte, error) {
 	// Re-implementing specific parts of Export for internal use
 	// Note: This duplicates logic from Export. ideally we refactor Export to use this.
 	// But to minimize changes to Export for now, I will implement this
 	// ensuring it populates the cache.
 
 	// Actually, let's just Refactor Export to be safe and clean.
-	return h.getOrGenerateData(slug, langCode, format, c)
+	return h.getOrGenerateData(slug, langCode, v, c)
+}
+
+// exportVariant identifies one rendering of a language export
+type exportVariant struct {
+	Format       string
+	EnvID        string // "" exports every key
+	Release      string // "" exports live data
+	ApprovedOnly bool
+	Fallback     bool
+}
+
+// cacheKey returns the cache key of the variant. The keys filled by a
+// fallback export are cached next to it under "<key>:filled".
+func (v exportVariant) cacheKey(slug, langCode string) string {
+	parts := []string{}
+	if v.EnvID != "" {
+		parts = append(parts, "env:"+v.EnvID)
+	}
+	if v.Release != "" {
+		parts = append(parts, "release:"+v.Release)
+	}
+	if v.ApprovedOnly {
+		parts = append(parts, "approved")
+	}
+	if v.Fallback {
+		parts = append(parts, "fallback")
+	}
+	return cache.ExportCacheKey(slug, langCode, v.Format, parts...)
+}
+
+// parseExportVariant reads the format, env, release, approved_only and fallback
+// query parameters.
+// The environment is selected by name; API keys pinned to an environment
+// default to it and cannot read any other. Writes the error response on failure.
+func (h *ExportHandler) parseExportVariant(c *fiber.Ctx, slug string) (exportVariant, error) {
+	v := exportVariant{
+		Format:       c.Query("format", "json"),
+		Release:      c.Query("release", ""),
+		ApprovedOnly: c.QueryBool("approved_only", false),
+		Fallback:     c.QueryBool("fallback", false),
+	}
+
+	// Check the API key's project before anything else, so a key can't probe
+	// another project's environments or read its cached exports
+	if authProjectID, ok := c.Locals("project_id").(string); ok {
+		var projectID string
+		err := h.DB.QueryRow(context.Background(),
+			`SELECT id FROM projects WHERE slug = $1`, slug,
+		).Scan(&projectID)
+		if err != nil || projectID != authProjectID {
+			c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "API key does not belong to this project"})
+			return v, fiber.NewError(fiber.StatusForbidden, "API key does not belong to this project")
+		}
+	}
+	if !isExportFormat(v.Format) {
+		c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": exportFormatError()})
+		return v, fiber.NewError(fiber.StatusBadRequest, exportFormatError())
+	}
+
+	if envName := c.Query("env", ""); envName != "" {
+		err := h.DB.QueryRow(context.Background(),
+			`SELECT e.id FROM environments e JOIN projects p ON p.id = e.project_id
+			 WHERE p.slug = $1 AND e.name = $2`, slug, envName,
+		).Scan(&v.EnvID)
+		if err != nil {
+			c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Environment not found"})
+			return v, fiber.NewError(fiber.StatusNotFound, "Environment not found")
+		}
+	}
+
+	if pinned, ok := c.Locals("api_key_env_id").(string); ok {
+		if v.EnvID == "" {
+			v.EnvID = pinned
+		} else if v.EnvID != pinned {
+			c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "API key is restricted to another environment"})
+			return v, fiber.NewError(fiber.StatusForbidden, "API key is restricted to another environment")
+		}
+	}
+
+	return v, nil
 }
 
 // getOrGenerateData handles the core logic: check cache, if miss -> generate & set cache
-func (h *ExportHandler) getOrGenerateData(slug, langCode, format string, c *fiber.Ctx) ([]byte, error) {
-	cacheKey := cache.CacheKey(slug, langCode, format)
+func (h *ExportHandler) getOrGenerateData(slug, langCode string, v exportVariant, c *fiber.Ctx) ([]byte, error) {
+	cacheKey := v.cacheKey(slug, langCode)
 	cached, err := h.Cache.Get(context.Background(), cacheKey)
 	if err == nil && cached != nil {
+		if v.Fallback {
+			filled, _ := h.Cache.Get(context.Background(), cacheKey+":filled")
+			setFallbackHeaders(c, strings.Fields(string(filled)))
+		}
 		c.Set("X-Cache", "HIT")
 		return cached, nil
 	}
@@ -161,6 +240,10 @@ func (h *ExportHandler) getOrGenerateData(slug, langCode, format string, c *fibe
 		return nil, fiber.NewError(fiber.StatusForbidden, "API key does not belong to this project")
 	}
 
+	if v.Release != "" {
+		return h.generateReleaseData(projectID, slug, langCode, cacheKey, v, c)
+	}
+
 	var languageID string
 	err = h.DB.QueryRow(context.Background(),
 		`SELECT id FROM languages WHERE project_id = $1 AND code = $2`, projectID, langCode,
@@ -171,53 +254,90 @@ func (h *ExportHandler) getOrGenerateData(slug, langCode, format string, c *fibe
 		return nil, err
 	}
 
-	rows, err := h.DB.Query(context.Background(),
-		`SELECT tk.key, t.value
-		 FROM translation_keys tk
-		 LEFT JOIN translations t ON t.key_id = tk.id AND t.language_id = $2
-		 WHERE tk.project_id = $1
-		 ORDER BY tk.key`,
-		projectID, languageID,
-	)
+	doc, err := loadExportDocument(h.DB, projectID, slug, languageID, langCode, v.EnvID, v.ApprovedOnly)
 	if err != nil {
 		c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch translations"})
 		return nil, err
 	}
-	defer rows.Close()
 
-	flatMap := make(map[string]string)
-	for rows.Next() {
-		var key string
-		var value *string
-		if err := rows.Scan(&key, &value); err != nil {
-			continue
-		}
-		if value != nil {
-			flatMap[key] = *value
-		} else {
-			flatMap[key] = ""
+	if v.Fallback {
+		if err := applyFallbacks(h.DB, doc, languageID, liveFallbacks(h.DB, projectID, v.EnvID, v.ApprovedOnly)); err != nil {
+			c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to apply fallback languages"})
+			return nil, err
 		}
 	}
 
-	nested := buildNestedMap(flatMap)
+	return h.encodeAndCache(doc, cacheKey, v, c)
+}
 
-	var data []byte
-	if format == "msgpack" {
-		data, err = msgpack.Marshal(nested)
-		if err != nil {
-			c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to encode MessagePack"})
+// generateReleaseData renders a language of a frozen release. Requests for an
+// environment (including pinned API keys) can only read releases taken from
+// that environment.
+func (h *ExportHandler) generateReleaseData(projectID, slug, langCode, cacheKey string, v exportVariant, c *fiber.Ctx) ([]byte, error) {
+	var releaseID, sourceLang string
+	var releaseEnv *string
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT id, env_id, source_language FROM releases WHERE project_id = $1 AND name = $2`,
+		projectID, v.Release,
+	).Scan(&releaseID, &releaseEnv, &sourceLang)
+	if err != nil {
+		c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Release not found"})
+		return nil, err
+	}
+	if v.EnvID != "" && (releaseEnv == nil || *releaseEnv != v.EnvID) {
+		c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Release belongs to another environment"})
+		return nil, fiber.NewError(fiber.StatusForbidden, "Release belongs to another environment")
+	}
+
+	var inRelease bool
+	_ = h.DB.QueryRow(context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM release_entries WHERE release_id = $1 AND language_code = $2)`,
+		releaseID, langCode,
+	).Scan(&inRelease)
+	if !inRelease {
+		c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Language not found in release"})
+		return nil, fiber.NewError(fiber.StatusNotFound, "Language not found in release")
+	}
+
+	doc, err := loadReleaseDocument(h.DB, releaseID, slug, sourceLang, langCode)
+	if err != nil {
+		c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch translations"})
+		return nil, err
+	}
+
+	if v.Fallback {
+		// Fallback chains were frozen with the release
+		var chain []string
+		err := h.DB.QueryRow(context.Background(),
+			`SELECT fallbacks FROM release_languages WHERE release_id = $1 AND language_code = $2`, releaseID, langCode,
+		).Scan(&chain)
+		if err != nil && err != pgx.ErrNoRows {
+			c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to apply fallback languages"})
 			return nil, err
 		}
-	} else {
-		data, err = json.MarshalIndent(nested, "", "  ")
-		if err != nil {
-			c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to encode JSON"})
+		if err := fillFallbacks(doc, chain, releaseFallbacks(h.DB, releaseID)); err != nil {
+			c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to apply fallback languages"})
 			return nil, err
 		}
 	}
 
+	return h.encodeAndCache(doc, cacheKey, v, c)
+}
+
+// encodeAndCache serializes a generated export and caches it for an hour
+func (h *ExportHandler) encodeAndCache(doc *exportDocument, cacheKey string, v exportVariant, c *fiber.Ctx) ([]byte, error) {
+	data, err := encodeExport(v.Format, doc)
+	if err != nil {
+		c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to encode export"})
+		return nil, err
+	}
+
 	// Cache the result for 1 hour
 	_ = h.Cache.Set(context.Background(), cacheKey, data, 1*time.Hour)
+	if v.Fallback {
+		_ = h.Cache.Set(context.Background(), cacheKey+":filled", []byte(strings.Join(doc.Filled, "\n")), 1*time.Hour)
+		setFallbackHeaders(c, doc.Filled)
+	}
 	c.Set("X-Cache", "MISS")
 	
 	return data, nil
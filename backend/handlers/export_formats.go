@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// exportFormats lists every format accepted by the export endpoints
+var exportFormats = []string{"json", "msgpack", "xliff12", "xliff20", "po", "pot", "android", "strings", "stringsdict"}
+
+// exportEntry is a single translation key prepared for export
+type exportEntry struct {
+	Key         string
+	Description string
+	Source      string // value in the project's default language
+	Value       string
+	// Plural keys carry one value per CLDR category; Source and Value
+	// mirror the "other" form
+	IsPlural    bool
+	Forms       map[string]string
+	SourceForms map[string]string
+}
+
+// exportDocument carries everything a format encoder needs
+type exportDocument struct {
+	ProjectSlug string
+	SourceLang  string
+	TargetLang  string
+	Entries     []exportEntry
+	Filled      []string // keys whose value came from a fallback language
+}
+
+// targetCategories returns the plural categories to export for an entry: the
+// ones required by the target language plus any extra forms that are filled
+func (d *exportDocument) targetCategories(e exportEntry) []string {
+	required := pluralRuleFor(d.TargetLang).Categories
+	categories := []string{}
+	for _, category := range pluralCategories {
+		if containsString(required, category) || e.Forms[category] != "" {
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
+
+// sourceForm returns the default-language text for a plural category,
+// falling back to the source "other" form
+func (e exportEntry) sourceForm(category string) string {
+	if v := e.SourceForms[category]; v != "" {
+		return v
+	}
+	return e.Source
+}
+
+func isExportFormat(format string) bool {
+	for _, f := range exportFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func exportFormatError() string {
+	return "Format must be one of: " + strings.Join(exportFormats, ", ")
+}
+
+// exportContentType returns the Content-Type header for a format
+func exportContentType(format string) string {
+	switch format {
+	case "msgpack":
+		return "application/x-msgpack"
+	case "xliff12":
+		return "application/x-xliff+xml"
+	case "xliff20":
+		return "application/xliff+xml"
+	case "po":
+		return "text/x-gettext-translation; charset=utf-8"
+	case "pot":
+		return "text/x-gettext-translation-template; charset=utf-8"
+	case "android":
+		return "application/xml; charset=utf-8"
+	case "strings":
+		return "text/plain; charset=utf-8"
+	case "stringsdict":
+		return "application/x-plist"
+	default:
+		return "application/json"
+	}
+}
+
+// exportFileExtension returns the file extension used for downloads
+func exportFileExtension(format string) string {
+	switch format {
+	case "msgpack":
+		return "msgpack"
+	case "xliff12", "xliff20":
+		return "xlf"
+	case "po", "pot", "strings", "stringsdict":
+		return format
+	case "android":
+		return "xml"
+	default:
+		return "json"
+	}
+}
+
+// encodeExport serializes an export document in the requested format
+func encodeExport(format string, doc *exportDocument) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(buildNestedMap(doc.flatMap()), "", "  ")
+	case "msgpack":
+		return msgpack.Marshal(buildNestedMap(doc.flatMap()))
+	case "xliff12":
+		return encodeXLIFF12(doc)
+	case "xliff20":
+		return encodeXLIFF20(doc)
+	case "po":
+		return encodePO(doc, false)
+	case "pot":
+		return encodePO(doc, true)
+	case "android":
+		return encodeAndroidStrings(doc)
+	case "strings":
+		return encodeAppleStrings(doc)
+	case "stringsdict":
+		return encodeAppleStringsdict(doc)
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// flatMap returns dot-notation keys and values. Plural keys expand to one
+// entry per category, e.g. "cart.items.one" and "cart.items.other".
+func (d *exportDocument) flatMap() map[string]string {
+	flatMap := make(map[string]string, len(d.Entries))
+	for _, e := range d.Entries {
+		if !e.IsPlural {
+			flatMap[e.Key] = e.Value
+			continue
+		}
+		for _, category := range d.targetCategories(e) {
+			flatMap[e.Key+"."+category] = e.Forms[category]
+		}
+	}
+	return flatMap
+}
+
+// loadExportDocument fetches all keys of a project with their values in the
+// target language and in the project's default (source) language.
+// When envID is set, only keys linked to that environment are included and the
+// environment's overrides replace the base values.
+func loadExportDocument(db *pgxpool.Pool, projectID, slug, languageID, langCode, envID string, approvedOnly bool) (*exportDocument, error) {
+	doc := &exportDocument{ProjectSlug: slug, TargetLang: langCode}
+
+	var sourceLangID string
+	err := db.QueryRow(context.Background(),
+		`SELECT id, code FROM languages WHERE project_id = $1 AND is_default = TRUE LIMIT 1`,
+		projectID,
+	).Scan(&sourceLangID, &doc.SourceLang)
+	if err != nil {
+		// No default language: the target language acts as its own source
+		sourceLangID = languageID
+		doc.SourceLang = langCode
+	}
+
+	value, forms := exportCellSQL("t", "ot", "$5")
+	sourceValue, sourceForms := exportCellSQL("s", "os", "$5")
+	query := `SELECT tk.key, COALESCE(tk.description, ''), tk.is_plural,
+		 	` + value + `, ` + forms + `,
+		 	` + sourceValue + `, ` + sourceForms + `
+		 FROM translation_keys tk
+		 LEFT JOIN translations t ON t.key_id = tk.id AND t.language_id = $2
+		 LEFT JOIN translations s ON s.key_id = tk.id AND s.language_id = $3
+		 LEFT JOIN translation_overrides ot ON ot.key_id = tk.id AND ot.language_id = $2 AND ot.env_id = $4::uuid
+		 LEFT JOIN translation_overrides os ON os.key_id = tk.id AND os.language_id = $3 AND os.env_id = $4::uuid
+		 WHERE tk.project_id = $1`
+	args := []interface{}{projectID, languageID, sourceLangID, nullableEnv(envID), approvedOnly}
+	if envID != "" {
+		query += ` AND tk.id IN (SELECT key_id FROM key_environments WHERE env_id = $4)`
+	}
+	query += ` ORDER BY tk.key`
+
+	rows, err := db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e exportEntry
+		var forms, sourceForms []byte
+		if err := rows.Scan(&e.Key, &e.Description, &e.IsPlural, &e.Value, &forms, &e.Source, &sourceForms); err != nil {
+			continue
+		}
+		if e.IsPlural {
+			e.Forms = decodePluralForms(forms)
+			e.SourceForms = decodePluralForms(sourceForms)
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+
+	return doc, rows.Err()
+}
+
+// fallbackSource returns the key, value and plural_forms rows of one
+// fallback language
+type fallbackSource func(code string) (pgx.Rows, error)
+
+// liveFallbacks reads fallback values from the current translations,
+// honouring the overrides of envID when it is set
+func liveFallbacks(db *pgxpool.Pool, projectID, envID string, approvedOnly bool) fallbackSource {
+	value, forms := exportCellSQL("t", "o", "$4")
+	return func(code string) (pgx.Rows, error) {
+		return db.Query(context.Background(),
+			`SELECT tk.key, `+value+`, `+forms+`
+			 FROM translation_keys tk
+			 JOIN languages l ON l.project_id = tk.project_id AND l.code = $2
+			 LEFT JOIN translations t ON t.key_id = tk.id AND t.language_id = l.id
+			 LEFT JOIN translation_overrides o ON o.key_id = tk.id AND o.language_id = l.id AND o.env_id = $3::uuid
+			 WHERE tk.project_id = $1`,
+			projectID, code, nullableEnv(envID), approvedOnly,
+		)
+	}
+}
+
+// exportCellSQL returns the SQL expressions of the exported value and
+// plural_forms of a translation (alias t) under an optional environment
+// override (alias o). When the boolean parameter approvedOnly is true, cells
+// that are not approved export their last approved value instead.
+func exportCellSQL(t, o, approvedOnly string) (value, forms string) {
+	pick := func(alias, column string) string {
+		return fmt.Sprintf("CASE WHEN %[1]s::boolean AND %[2]s.status <> 'approved' THEN %[2]s.approved_%[3]s ELSE %[2]s.%[3]s END",
+			approvedOnly, alias, column)
+	}
+	override := pick(o, "value")
+	value = fmt.Sprintf("COALESCE(%s, %s, '')", override, pick(t, "value"))
+	forms = fmt.Sprintf("CASE WHEN %s IS NOT NULL THEN %s ELSE %s END", override, pick(o, "plural_forms"), pick(t, "plural_forms"))
+	return value, forms
+}
+
+// applyFallbacks fills untranslated values of a document from the target
+// language's current fallback chain
+func applyFallbacks(db *pgxpool.Pool, doc *exportDocument, languageID string, source fallbackSource) error {
+	var chain []string
+	if err := db.QueryRow(context.Background(),
+		`SELECT fallbacks FROM languages WHERE id = $1`, languageID,
+	).Scan(&chain); err != nil {
+		return err
+	}
+	return fillFallbacks(doc, chain, source)
+}
+
+// fillFallbacks fills untranslated values of a document from the languages
+// of chain, trying each in order. Plural keys are filled per category
+// required by the target language.
+func fillFallbacks(doc *exportDocument, chain []string, source fallbackSource) error {
+	filled := make(map[string]bool)
+	required := pluralRuleFor(doc.TargetLang).Categories
+	for _, code := range chain {
+		rows, err := source(code)
+		if err != nil {
+			return err
+		}
+		values := make(map[string]string)
+		forms := make(map[string]map[string]string)
+		for rows.Next() {
+			var key, value string
+			var pluralForms []byte
+			if err := rows.Scan(&key, &value, &pluralForms); err != nil {
+				continue
+			}
+			values[key] = value
+			if pluralForms != nil {
+				forms[key] = decodePluralForms(pluralForms)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for i := range doc.Entries {
+			e := &doc.Entries[i]
+			if !e.IsPlural {
+				if e.Value == "" && values[e.Key] != "" {
+					e.Value = values[e.Key]
+					filled[e.Key] = true
+				}
+				continue
+			}
+			if e.Forms == nil {
+				e.Forms = map[string]string{}
+			}
+			for _, category := range required {
+				if e.Forms[category] != "" {
+					continue
+				}
+				value := forms[e.Key][category]
+				if value == "" {
+					value = forms[e.Key]["other"]
+				}
+				if value == "" {
+					value = values[e.Key]
+				}
+				if value != "" {
+					e.Forms[category] = value
+					filled[e.Key] = true
+				}
+			}
+			e.Value = e.Forms["other"]
+		}
+	}
+
+	for _, e := range doc.Entries {
+		if filled[e.Key] {
+			doc.Filled = append(doc.Filled, e.Key)
+		}
+	}
+	return nil
+}
+
+// nullableEnv maps an empty environment ID to NULL so optional environment
+// joins match nothing
+func nullableEnv(envID string) interface{} {
+	if envID == "" {
+		return nil
+	}
+	return envID
+}
+
+// maxFallbackKeysHeader keeps X-Fallback-Keys well below the 8 KB header
+// limits of common proxies and clients
+const maxFallbackKeysHeader = 4096
+
+// setFallbackHeaders reports the keys filled from fallback languages. The key
+// list is cut at whole keys once it would pass maxFallbackKeysHeader bytes,
+// and X-Fallback-Keys-Truncated is set; X-Fallback-Count is always complete.
+func setFallbackHeaders(c *fiber.Ctx, filled []string) {
+	c.Set("X-Fallback-Count", strconv.Itoa(len(filled)))
+	size := 0
+	for i, key := range filled {
+		size += len(key) + 1
+		if size-1 > maxFallbackKeysHeader {
+			c.Set("X-Fallback-Keys", strings.Join(filled[:i], ","))
+			c.Set("X-Fallback-Keys-Truncated", "true")
+			return
+		}
+	}
+	c.Set("X-Fallback-Keys", strings.Join(filled, ","))
+}
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// poMessage is a single entry of a gettext catalog
+type poMessage struct {
+	Comments []string
+	Context  string
+	ID       string
+	IDPlural string
+	Str      []string
+	Fuzzy    bool
+	hasID    bool
+}
+
+// encodePO renders an export document as a gettext catalog. Keys become
+// msgctxt, default-language values become msgid and key descriptions become
+// translator comments. Plural keys become msgid_plural entries ordered by the
+// language's Plural-Forms. When template is true a .pot file is produced with
+// empty msgstr values.
+func encodePO(doc *exportDocument, template bool) ([]byte, error) {
+	rule := pluralRuleFor(doc.TargetLang)
+	if template {
+		rule = pluralRuleFor(doc.SourceLang)
+	}
+
+	var b strings.Builder
+	b.WriteString("msgid \"\"\nmsgstr \"\"\n")
+	fmt.Fprintf(&b, "\"Project-Id-Version: %s\\n\"\n", escapePO(doc.ProjectSlug))
+	if !template {
+		fmt.Fprintf(&b, "\"Language: %s\\n\"\n", escapePO(doc.TargetLang))
+	}
+	b.WriteString("\"MIME-Version: 1.0\\n\"\n")
+	b.WriteString("\"Content-Type: text/plain; charset=UTF-8\\n\"\n")
+	b.WriteString("\"Content-Transfer-Encoding: 8bit\\n\"\n")
+	if template {
+		b.WriteString("\"Plural-Forms: nplurals=INTEGER; plural=EXPRESSION;\\n\"\n")
+	} else {
+		fmt.Fprintf(&b, "\"Plural-Forms: %s\\n\"\n", rule.GettextForms)
+	}
+	fmt.Fprintf(&b, "\"X-Source-Language: %s\\n\"\n", escapePO(doc.SourceLang))
+
+	for _, msg := range poMessagesFromEntries(doc.Entries, rule, template) {
+		b.WriteString("\n")
+		for _, comment := range msg.Comments {
+			b.WriteString("# " + comment + "\n")
+		}
+		writePOString(&b, "msgctxt", msg.Context)
+		writePOString(&b, "msgid", msg.ID)
+		if msg.IDPlural != "" {
+			writePOString(&b, "msgid_plural", msg.IDPlural)
+			for i, str := range msg.Str {
+				writePOString(&b, fmt.Sprintf("msgstr[%d]", i), str)
+			}
+		} else {
+			writePOString(&b, "msgstr", msg.Str[0])
+		}
+	}
+
+	return []byte(b.String()), nil
+}
+
+// poMessagesFromEntries converts export entries to gettext messages
+func poMessagesFromEntries(entries []exportEntry, rule pluralRule, template bool) []poMessage {
+	messages := []poMessage{}
+	for _, e := range entries {
+		if e.IsPlural {
+			messages = append(messages, pluralPOMessage(e, rule, template))
+			continue
+		}
+
+		msg := poMessage{Context: e.Key, ID: e.Source, Str: []string{""}}
+		if msg.ID == "" {
+			msg.ID = e.Key
+		}
+		if !template {
+			msg.Str[0] = e.Value
+		}
+		msg.Comments = poComments(e.Description)
+		messages = append(messages, msg)
+	}
+
+	return messages
+}
+
+func pluralPOMessage(e exportEntry, rule pluralRule, template bool) poMessage {
+	msg := poMessage{
+		Context:  e.Key,
+		ID:       e.sourceForm("one"),
+		IDPlural: e.Source,
+		Str:      make([]string, len(rule.GettextCategories)),
+	}
+	if msg.ID == "" {
+		msg.ID = e.Key
+	}
+	if msg.IDPlural == "" {
+		msg.IDPlural = msg.ID
+	}
+	if template {
+		msg.Str = []string{"", ""}
+	} else {
+		for i, category := range rule.GettextCategories {
+			msg.Str[i] = e.Forms[category]
+		}
+	}
+	msg.Comments = poComments(e.Description)
+	return msg
+}
+
+func poComments(description string) []string {
+	if description == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(description, "\n"), "\n")
+}
+
+func writePOString(b *strings.Builder, keyword, value string) {
+	if keyword == "msgctxt" && value == "" {
+		return
+	}
+	trimmed := strings.TrimSuffix(value, "\n")
+	if !strings.Contains(trimmed, "\n") {
+		fmt.Fprintf(b, "%s \"%s\"\n", keyword, escapePO(value))
+		return
+	}
+	b.WriteString(keyword + " \"\"\n")
+	for _, line := range strings.SplitAfter(value, "\n") {
+		if line != "" {
+			fmt.Fprintf(b, "\"%s\"\n", escapePO(line))
+		}
+	}
+}
+
+var poEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+func escapePO(s string) string {
+	return poEscaper.Replace(s)
+}
+
+func unescapePO(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func parsePOQuoted(s string, line int) (string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("line %d: expected quoted string", line)
+	}
+	return unescapePO(s[1 : len(s)-1]), nil
+}
+
+// parsePO reads a gettext .po file and returns the Language header value and
+// all non-header messages. Obsolete (#~) entries are ignored.
+func parsePO(data []byte) (string, []poMessage, error) {
+	messages := []poMessage{}
+	language := ""
+
+	var cur poMessage
+	var field *string
+	flush := func() {
+		if cur.hasID {
+			if cur.ID == "" && cur.Context == "" {
+				// Header entry
+				if len(cur.Str) > 0 {
+					for _, h := range strings.Split(cur.Str[0], "\n") {
+						if name, value, ok := strings.Cut(h, ":"); ok && strings.TrimSpace(name) == "Language" {
+							language = strings.TrimSpace(value)
+						}
+					}
+				}
+			} else {
+				messages = append(messages, cur)
+			}
+		}
+		cur = poMessage{}
+		field = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "#~"):
+			continue
+		case strings.HasPrefix(line, "#"):
+			if cur.hasID {
+				flush()
+			}
+			switch {
+			case strings.HasPrefix(line, "#,"):
+				if strings.Contains(line, "fuzzy") {
+					cur.Fuzzy = true
+				}
+			case strings.HasPrefix(line, "#."):
+				cur.Comments = append(cur.Comments, strings.TrimSpace(line[2:]))
+			case line == "#" || strings.HasPrefix(line, "# "):
+				cur.Comments = append(cur.Comments, strings.TrimSpace(line[1:]))
+			}
+		case strings.HasPrefix(line, "msgctxt "):
+			if cur.hasID {
+				flush()
+			}
+			value, err := parsePOQuoted(line[len("msgctxt "):], lineNo)
+			if err != nil {
+				return "", nil, err
+			}
+			cur.Context = value
+			field = &cur.Context
+		case strings.HasPrefix(line, "msgid_plural "):
+			value, err := parsePOQuoted(line[len("msgid_plural "):], lineNo)
+			if err != nil {
+				return "", nil, err
+			}
+			cur.IDPlural = value
+			field = &cur.IDPlural
+		case strings.HasPrefix(line, "msgid "):
+			if cur.hasID {
+				flush()
+			}
+			value, err := parsePOQuoted(line[len("msgid "):], lineNo)
+			if err != nil {
+				return "", nil, err
+			}
+			cur.ID = value
+			cur.hasID = true
+			field = &cur.ID
+		case strings.HasPrefix(line, "msgstr["):
+			end := strings.Index(line, "]")
+			if end < 0 {
+				return "", nil, fmt.Errorf("line %d: malformed msgstr index", lineNo)
+			}
+			idx, err := strconv.Atoi(line[len("msgstr["):end])
+			if err != nil || idx < 0 || idx > 9 {
+				return "", nil, fmt.Errorf("line %d: malformed msgstr index", lineNo)
+			}
+			value, err := parsePOQuoted(line[end+1:], lineNo)
+			if err != nil {
+				return "", nil, err
+			}
+			for len(cur.Str) <= idx {
+				cur.Str = append(cur.Str, "")
+			}
+			cur.Str[idx] = value
+			field = &cur.Str[idx]
+		case strings.HasPrefix(line, "msgstr "):
+			value, err := parsePOQuoted(line[len("msgstr "):], lineNo)
+			if err != nil {
+				return "", nil, err
+			}
+			cur.Str = []string{value}
+			field = &cur.Str[0]
+		case strings.HasPrefix(line, `"`):
+			if field == nil {
+				return "", nil, fmt.Errorf("line %d: unexpected string continuation", lineNo)
+			}
+			value, err := parsePOQuoted(line, lineNo)
+			if err != nil {
+				return "", nil, err
+			}
+			*field += value
+		default:
+			return "", nil, fmt.Errorf("line %d: unrecognized syntax", lineNo)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, err
+	}
+	flush()
+
+	return language, messages, nil
+}
+
+// poImportEntries converts parsed gettext messages to import entries. msgctxt
+// is used as the key (falling back to msgid); plural messages map msgstr[n] to
+// CLDR categories using the language's gettext plural order. Fuzzy and
+// untranslated messages are skipped.
+func poImportEntries(messages []poMessage, langCode string) []importEntry {
+	categories := pluralRuleFor(langCode).GettextCategories
+	entries := []importEntry{}
+	for _, msg := range messages {
+		if msg.Fuzzy || len(msg.Str) == 0 {
+			continue
+		}
+		key := msg.Context
+		if key == "" {
+			key = msg.ID
+		}
+		description := strings.Join(msg.Comments, "\n")
+
+		if msg.IDPlural == "" {
+			if msg.Str[0] != "" {
+				entries = append(entries, importEntry{Key: key, Value: msg.Str[0], Description: description})
+			}
+			continue
+		}
+		forms := make(map[string]string)
+		for i, str := range msg.Str {
+			if i < len(categories) && str != "" {
+				forms[categories[i]] = str
+			}
+		}
+		if len(forms) > 0 {
+			entries = append(entries, importEntry{Key: key, Value: forms["other"], Description: description, Forms: forms})
+		}
+	}
+	return entries
+}
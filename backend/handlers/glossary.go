@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"translate-management/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var errUnknownLanguage = errors.New("Language not found")
+
+type GlossaryHandler struct {
+	DB *pgxpool.Pool
+}
+
+func NewGlossaryHandler(db *pgxpool.Pool) *GlossaryHandler {
+	return &GlossaryHandler{DB: db}
+}
+
+// containsTerm reports whether text contains term. With wholeWord the match
+// must not be part of a longer word; translations are matched without it, as
+// scripts such as Japanese do not separate words.
+func containsTerm(text, term string, caseSensitive, wholeWord bool) bool {
+	if term == "" {
+		return false
+	}
+	if !caseSensitive {
+		text, term = strings.ToLower(text), strings.ToLower(term)
+	}
+	for offset := 0; offset < len(text); {
+		i := strings.Index(text[offset:], term)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(term)
+		if !wholeWord || (isWordEdge(text[:start], true) && isWordEdge(text[end:], false)) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		offset = start + size
+	}
+	return false
+}
+
+// isWordEdge reports whether the text next to a match (before it when last is
+// set, after it otherwise) does not continue a word
+func isWordEdge(text string, last bool) bool {
+	var r rune
+	if last {
+		r, _ = utf8.DecodeLastRuneInString(text)
+	} else {
+		r, _ = utf8.DecodeRuneInString(text)
+	}
+	return r == utf8.RuneError || !(unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// checkGlossaryCell appends an issue for every glossary term used by the
+// default-language value whose approved translation is missing from the
+// translation (one per category for plurals). Terms without an approved
+// translation for the cell's language are not checked.
+func checkGlossaryCell(issues []models.GlossaryIssue, cell models.GlossaryIssue, terms []models.GlossaryTerm, source, value string, sourceForms, forms map[string]string) []models.GlossaryIssue {
+	forEachCellValue(source, value, sourceForms, forms, func(category, source, value string) {
+		for _, term := range terms {
+			expected := term.Translations[cell.LanguageID]
+			if term.DoNotTranslate {
+				expected = term.Term
+			}
+			if expected == "" || !containsTerm(source, term.Term, term.CaseSensitive, true) {
+				continue
+			}
+			if containsTerm(value, expected, term.CaseSensitive, false) {
+				continue
+			}
+			issue := cell
+			issue.Category = category
+			issue.TermID, issue.Term, issue.Expected = term.ID, term.Term, expected
+			issues = append(issues, issue)
+		}
+	})
+	return issues
+}
+
+// findGlossaryIssues checks stored translations against the glossary
+func findGlossaryIssues(cells []qaCell, terms []models.GlossaryTerm) []models.GlossaryIssue {
+	issues := []models.GlossaryIssue{}
+	if len(terms) == 0 {
+		return issues
+	}
+	for _, c := range cells {
+		issue := models.GlossaryIssue{KeyID: c.KeyID, Key: c.Key, LanguageID: c.LanguageID, LanguageCode: c.LanguageCode}
+		issues = checkGlossaryCell(issues, issue, terms, c.Source, c.Value, c.SourceForms, c.Forms)
+	}
+	return issues
+}
+
+// loadGlossary returns the terms of a project with their translations,
+// optionally limited to one term
+func loadGlossary(db *pgxpool.Pool, projectID, termID string) ([]models.GlossaryTerm, error) {
+	query := `SELECT id, project_id, term, description, case_sensitive, do_not_translate, created_at, updated_at
+		 FROM glossary_terms WHERE project_id = $1`
+	args := []interface{}{projectID}
+	if termID != "" {
+		query += ` AND id = $2`
+		args = append(args, termID)
+	}
+	query += ` ORDER BY term ASC`
+
+	rows, err := db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terms := []models.GlossaryTerm{}
+	index := make(map[string]int)
+	for rows.Next() {
+		var t models.GlossaryTerm
+		if err := rows.Scan(&t.ID, &t.ProjectID, &t.Term, &t.Description, &t.CaseSensitive, &t.DoNotTranslate, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			continue
+		}
+		t.Translations = make(map[string]string)
+		index[t.ID] = len(terms)
+		terms = append(terms, t)
+	}
+	if err := rows.Err(); err != nil || len(terms) == 0 {
+		return terms, err
+	}
+
+	tRows, err := db.Query(context.Background(),
+		`SELECT gt.term_id, gt.language_id, gt.translation
+		 FROM glossary_translations gt
+		 JOIN glossary_terms g ON g.id = gt.term_id
+		 WHERE g.project_id = $1`,
+		projectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer tRows.Close()
+
+	for tRows.Next() {
+		var termID, langID, translation string
+		if err := tRows.Scan(&termID, &langID, &translation); err != nil {
+			continue
+		}
+		if i, ok := index[termID]; ok {
+			terms[i].Translations[langID] = translation
+		}
+	}
+	return terms, tRows.Err()
+}
+
+// verifyProjectRole checks project membership and returns the user's role.
+// Writes a 404 if the project is not found or the user is not a member.
+func (h *GlossaryHandler) verifyProjectRole(c *fiber.Ctx, projectID, userID string) (string, error) {
+	var role string
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT
+			CASE
+				WHEN p.created_by = $2 THEN 'owner'
+				ELSE COALESCE(pm.role, '')
+			END as role
+		FROM projects p
+		LEFT JOIN project_members pm ON p.id = pm.project_id AND pm.user_id = $2
+		WHERE p.id = $1 AND (p.created_by = $2 OR pm.user_id = $2)`,
+		projectID, userID).Scan(&role)
+	if err != nil {
+		c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or access denied"})
+		return "", err
+	}
+	return role, nil
+}
+
+// saveGlossaryTranslations sets or, for empty values, removes the approved
+// translations of a term. Languages must belong to the project.
+func saveGlossaryTranslations(tx pgx.Tx, projectID, termID string, translations map[string]string) error {
+	for langID, translation := range translations {
+		var err error
+		if translation == "" {
+			_, err = tx.Exec(context.Background(),
+				`DELETE FROM glossary_translations WHERE term_id = $1 AND language_id = $2`, termID, langID,
+			)
+		} else {
+			var tag pgconn.CommandTag
+			tag, err = tx.Exec(context.Background(),
+				`INSERT INTO glossary_translations (term_id, language_id, translation)
+				 SELECT $1, id, $3 FROM languages WHERE id = $2 AND project_id = $4
+				 ON CONFLICT (term_id, language_id) DO UPDATE SET translation = EXCLUDED.translation`,
+				termID, langID, translation, projectID,
+			)
+			if err == nil && tag.RowsAffected() == 0 {
+				return errUnknownLanguage
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the glossary of a project
+func (h *GlossaryHandler) List(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	if _, err := h.verifyProjectRole(c, projectID, userID); err != nil {
+		return err
+	}
+
+	terms, err := loadGlossary(h.DB, projectID, "")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch glossary"})
+	}
+	return c.JSON(terms)
+}
+
+// Create adds a term to the glossary
+func (h *GlossaryHandler) Create(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	role, err := h.verifyProjectRole(c, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if role != "owner" && role != "editor" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	var req models.CreateGlossaryTermRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	req.Term = strings.TrimSpace(req.Term)
+	if req.Term == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Term is required"})
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	var termID string
+	err = tx.QueryRow(context.Background(),
+		`INSERT INTO glossary_terms (project_id, term, description, case_sensitive, do_not_translate, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (project_id, term) DO NOTHING
+		 RETURNING id`,
+		projectID, req.Term, req.Description, req.CaseSensitive, req.DoNotTranslate, userID,
+	).Scan(&termID)
+	if err == pgx.ErrNoRows {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Term already exists in the glossary"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create term"})
+	}
+
+	if err := saveGlossaryTranslations(tx, projectID, termID, req.Translations); err == errUnknownLanguage {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Language not found"})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save term translations"})
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
+
+	terms, err := loadGlossary(h.DB, projectID, termID)
+	if err != nil || len(terms) == 0 {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch term"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(terms[0])
+}
+
+// Update changes a glossary term
+func (h *GlossaryHandler) Update(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	termID := c.Params("termId")
+	userID := c.Locals("user_id").(string)
+
+	role, err := h.verifyProjectRole(c, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if role != "owner" && role != "editor" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	var req models.UpdateGlossaryTermRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.Term != nil {
+		trimmed := strings.TrimSpace(*req.Term)
+		if trimmed == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Term is required"})
+		}
+		req.Term = &trimmed
+
+		var taken bool
+		_ = h.DB.QueryRow(context.Background(),
+			`SELECT EXISTS(SELECT 1 FROM glossary_terms WHERE project_id = $1 AND term = $2 AND id <> $3)`,
+			projectID, trimmed, termID,
+		).Scan(&taken)
+		if taken {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Term already exists in the glossary"})
+		}
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	tag, err := tx.Exec(context.Background(),
+		`UPDATE glossary_terms SET
+		 	term = COALESCE($3, term),
+		 	description = COALESCE($4, description),
+		 	case_sensitive = COALESCE($5, case_sensitive),
+		 	do_not_translate = COALESCE($6, do_not_translate),
+		 	updated_at = NOW()
+		 WHERE id = $1 AND project_id = $2`,
+		termID, projectID, req.Term, req.Description, req.CaseSensitive, req.DoNotTranslate,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update term"})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Term not found"})
+	}
+
+	if err := saveGlossaryTranslations(tx, projectID, termID, req.Translations); err == errUnknownLanguage {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Language not found"})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save term translations"})
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
+
+	terms, err := loadGlossary(h.DB, projectID, termID)
+	if err != nil || len(terms) == 0 {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch term"})
+	}
+	return c.JSON(terms[0])
+}
+
+// Delete removes a term from the glossary
+func (h *GlossaryHandler) Delete(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	termID := c.Params("termId")
+	userID := c.Locals("user_id").(string)
+
+	role, err := h.verifyProjectRole(c, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if role != "owner" && role != "editor" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	tag, err := h.DB.Exec(context.Background(),
+		`DELETE FROM glossary_terms WHERE id = $1 AND project_id = $2`, termID, projectID,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete term"})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Term not found"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Term deleted"})
+}
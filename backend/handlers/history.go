@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"translate-management/cache"
+	"translate-management/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type HistoryHandler struct {
+	DB    *pgxpool.Pool
+	Cache *cache.RedisClient
+}
+
+func NewHistoryHandler(db *pgxpool.Pool, rdb *cache.RedisClient) *HistoryHandler {
+	return &HistoryHandler{DB: db, Cache: rdb}
+}
+
+// translationCell is the stored state of a translation or environment override
+type translationCell struct {
+	Value string
+	Forms []byte // plural_forms JSON, nil for plain values
+}
+
+// readTranslationCell returns the current state of a cell, or nil when it
+// does not exist. A non-empty envID reads the environment override.
+func readTranslationCell(tx pgx.Tx, keyID, languageID, envID string) (*translationCell, error) {
+	query := `SELECT value, plural_forms FROM translations WHERE key_id = $1 AND language_id = $2`
+	args := []interface{}{keyID, languageID}
+	if envID != "" {
+		query = `SELECT value, plural_forms FROM translation_overrides WHERE key_id = $1 AND language_id = $2 AND env_id = $3`
+		args = append(args, envID)
+	}
+
+	var cell translationCell
+	err := tx.QueryRow(context.Background(), query, args...).Scan(&cell.Value, &cell.Forms)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cell, nil
+}
+
+// recordHistory appends a history entry when a cell changed from old to its
+// current state. Call readTranslationCell before the write to obtain old.
+// A changed cell goes back to draft (or untranslated once emptied) for review,
+// except on promotion, which copies the review state of the source. Action
+// "machine" marks the new value as machine-translated.
+func recordHistory(tx pgx.Tx, keyID, languageID, envID, userID, action string, old *translationCell, revertedFrom *string) error {
+	cur, err := readTranslationCell(tx, keyID, languageID, envID)
+	if err != nil {
+		return err
+	}
+	if old == nil && cur == nil {
+		return nil
+	}
+	if old != nil && cur != nil && old.Value == cur.Value && bytes.Equal(old.Forms, cur.Forms) {
+		return nil
+	}
+
+	var oldValue, newValue *string
+	var oldForms, newForms []byte
+	if old != nil {
+		oldValue, oldForms = &old.Value, old.Forms
+	}
+	if cur != nil {
+		newValue, newForms = &cur.Value, cur.Forms
+	}
+	_, err = tx.Exec(context.Background(),
+		`INSERT INTO translation_history
+		 	(key_id, language_id, env_id, action, old_value, new_value, old_plural_forms, new_plural_forms, reverted_from, changed_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		keyID, languageID, nullableEnv(envID), action, oldValue, newValue, oldForms, newForms, revertedFrom, nullableUser(userID),
+	)
+	if err != nil || cur == nil || action == "promote" {
+		return err
+	}
+	return resetReviewStatus(tx, keyID, languageID, envID, cur, action == "machine")
+}
+
+// nullableUser stores changes without a user (API key writes) as NULL
+func nullableUser(userID string) interface{} {
+	if userID == "" {
+		return nil
+	}
+	return userID
+}
+
+// List returns the change history of a key, newest first. ?lang=xx and
+// ?env_id=... narrow it to one language or environment.
+func (h *HistoryHandler) List(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	keyID := c.Params("keyId")
+	userID := c.Locals("user_id").(string)
+
+	// Verify project membership
+	var exists bool
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT EXISTS(
+			SELECT 1 FROM projects p
+			LEFT JOIN project_members pm ON p.id = pm.project_id
+			WHERE p.id = $1 AND (p.created_by = $2 OR pm.user_id = $2)
+		)`, projectID, userID).Scan(&exists)
+	if err != nil || !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or access denied"})
+	}
+
+	err = h.DB.QueryRow(context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM translation_keys WHERE id = $1 AND project_id = $2)`, keyID, projectID,
+	).Scan(&exists)
+	if err != nil || !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Key not found"})
+	}
+
+	query := `SELECT th.id, th.key_id, th.language_id, l.code, th.env_id, th.action,
+			th.old_value, th.new_value, th.old_plural_forms, th.new_plural_forms,
+			th.reverted_from, th.changed_by, COALESCE(u.username, ''), th.created_at
+		 FROM translation_history th
+		 JOIN languages l ON l.id = th.language_id
+		 LEFT JOIN users u ON u.id = th.changed_by
+		 WHERE th.key_id = $1`
+	args := []interface{}{keyID}
+	if lang := c.Query("lang", ""); lang != "" {
+		args = append(args, lang)
+		query += ` AND l.code = $2`
+	}
+	if envID := c.Query("env_id", ""); envID != "" {
+		args = append(args, envID)
+		query += ` AND th.env_id = $` + fmt.Sprint(len(args))
+	}
+	query += ` ORDER BY th.created_at DESC, th.seq DESC`
+
+	rows, err := h.DB.Query(context.Background(), query, args...)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch history"})
+	}
+	defer rows.Close()
+
+	revisions := []models.TranslationRevision{}
+	for rows.Next() {
+		var r models.TranslationRevision
+		var oldForms, newForms []byte
+		if err := rows.Scan(&r.ID, &r.KeyID, &r.LanguageID, &r.LanguageCode, &r.EnvID, &r.Action,
+			&r.OldValue, &r.NewValue, &oldForms, &newForms,
+			&r.RevertedFrom, &r.ChangedBy, &r.ChangedByName, &r.CreatedAt); err != nil {
+			continue
+		}
+		if oldForms != nil {
+			r.OldPluralForms = decodePluralForms(oldForms)
+		}
+		if newForms != nil {
+			r.NewPluralForms = decodePluralForms(newForms)
+		}
+		revisions = append(revisions, r)
+	}
+
+	return c.JSON(revisions)
+}
+
+// Revert restores the value a cell had right after the given revision and
+// records the revert as a new history entry
+func (h *HistoryHandler) Revert(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	keyID := c.Params("keyId")
+	revisionID := c.Params("revisionId")
+	userID := c.Locals("user_id").(string)
+
+	// Verify project membership and role
+	var role string
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT
+			CASE
+				WHEN p.created_by = $2 THEN 'owner'
+				ELSE pm.role
+			END as role
+		FROM projects p
+		LEFT JOIN project_members pm ON p.id = pm.project_id AND pm.user_id = $2
+		WHERE p.id = $1`,
+		projectID, userID).Scan(&role)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or access denied"})
+	}
+	if role != "owner" && role != "editor" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	var languageID string
+	var envID *string
+	var value *string
+	var forms []byte
+	err = h.DB.QueryRow(context.Background(),
+		`SELECT th.language_id, th.env_id, th.new_value, th.new_plural_forms
+		 FROM translation_history th
+		 JOIN translation_keys tk ON tk.id = th.key_id
+		 WHERE th.id = $1 AND th.key_id = $2 AND tk.project_id = $3`,
+		revisionID, keyID, projectID,
+	).Scan(&languageID, &envID, &value, &forms)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Revision not found"})
+	}
+	env := ""
+	if envID != nil {
+		env = *envID
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	old, err := readTranslationCell(tx, keyID, languageID, env)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read translation"})
+	}
+
+	switch {
+	case value == nil && env != "":
+		_, err = tx.Exec(context.Background(),
+			`DELETE FROM translation_overrides WHERE key_id = $1 AND language_id = $2 AND env_id = $3`,
+			keyID, languageID, env,
+		)
+	case value == nil:
+		_, err = tx.Exec(context.Background(),
+			`DELETE FROM translations WHERE key_id = $1 AND language_id = $2`, keyID, languageID,
+		)
+	case env != "":
+		_, err = tx.Exec(context.Background(),
+			`INSERT INTO translation_overrides (key_id, language_id, env_id, value, plural_forms, updated_by)
+			 VALUES ($1, $2, $3, $4, $5, $6)
+			 ON CONFLICT (key_id, language_id, env_id)
+			 DO UPDATE SET value = EXCLUDED.value, plural_forms = EXCLUDED.plural_forms, updated_at = NOW(), updated_by = EXCLUDED.updated_by`,
+			keyID, languageID, env, *value, forms, userID,
+		)
+	default:
+		_, err = tx.Exec(context.Background(),
+			`INSERT INTO translations (key_id, language_id, value, plural_forms, updated_by)
+			 VALUES ($1, $2, $3, $4, $5)
+			 ON CONFLICT (key_id, language_id)
+			 DO UPDATE SET value = EXCLUDED.value, plural_forms = EXCLUDED.plural_forms, updated_at = NOW(), updated_by = EXCLUDED.updated_by`,
+			keyID, languageID, *value, forms, userID,
+		)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revert translation"})
+	}
+
+	if err := recordHistory(tx, keyID, languageID, env, userID, "revert", old, &revisionID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record history"})
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit revert"})
+	}
+
+	var slug string
+	_ = h.DB.QueryRow(context.Background(), "SELECT slug FROM projects WHERE id = $1", projectID).Scan(&slug)
+	if slug != "" {
+		_ = h.Cache.DeleteByPattern(context.Background(), cache.ProjectCachePattern(slug))
+	}
+
+	return c.JSON(fiber.Map{"message": "Translation reverted"})
+}
@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"unicode"
+
+	"translate-management/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// icuError describes a malformed ICU MessageFormat message
+type icuError struct {
+	Offset  int // character offset into the message
+	Message string
+}
+
+func (e *icuError) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.Message, e.Offset)
+}
+
+var icuSimpleTypes = map[string]bool{
+	"number": true, "date": true, "time": true,
+	"spellout": true, "ordinal": true, "duration": true,
+}
+
+// validateICU parses a message as ICU MessageFormat and returns the first
+// syntax error, or nil when the message is well-formed
+func validateICU(message string) *icuError {
+	p := &icuParser{src: []rune(message)}
+	if err := p.parseMessage(0, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+// icuArguments returns the argument names of a well-formed ICU message in
+// order of appearance, including arguments nested in plural and select
+// branches but not the branch text itself. ok is false when the message
+// doesn't parse.
+func icuArguments(message string) (args []string, ok bool) {
+	p := &icuParser{src: []rune(message)}
+	if err := p.parseMessage(0, false); err != nil {
+		return nil, false
+	}
+	return p.args, true
+}
+
+type icuParser struct {
+	src  []rune
+	pos  int
+	args []string // argument names, in order of appearance
+}
+
+func (p *icuParser) errorf(offset int, format string, args ...interface{}) *icuError {
+	return &icuError{Offset: offset, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *icuParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *icuParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *icuParser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *icuParser) readIdentifier() string {
+	start := p.pos
+	for !p.eof() {
+		r := p.src[p.pos]
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			break
+		}
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+// skipQuoted handles ICU apostrophe quoting. A doubled apostrophe is a literal
+// apostrophe; an apostrophe before a syntax character starts a quoted literal
+// that runs to the next single apostrophe.
+func (p *icuParser) skipQuoted(inPlural bool) {
+	p.pos++ // opening apostrophe
+	if p.eof() {
+		return
+	}
+	next := p.src[p.pos]
+	if next == '\'' {
+		p.pos++
+		return
+	}
+	if next != '{' && next != '}' && next != '|' && !(inPlural && next == '#') {
+		return
+	}
+	for !p.eof() {
+		if p.src[p.pos] == '\'' {
+			if p.pos+1 < len(p.src) && p.src[p.pos+1] == '\'' {
+				p.pos += 2
+				continue
+			}
+			p.pos++
+			return
+		}
+		p.pos++
+	}
+}
+
+// skipMustache consumes an i18next-style "{{name}}" placeholder, which is
+// not ICU syntax but is common in the same projects, as literal text
+func (p *icuParser) skipMustache() bool {
+	if p.pos+1 >= len(p.src) || p.src[p.pos+1] != '{' {
+		return false
+	}
+	for end := p.pos + 2; end+1 < len(p.src); end++ {
+		switch {
+		case p.src[end] == '{':
+			return false
+		case p.src[end] == '}' && p.src[end+1] == '}':
+			p.pos = end + 2
+			return true
+		}
+	}
+	return false
+}
+
+// parseMessage consumes message text up to an unmatched '}' (left for the
+// caller) or the end of input
+func (p *icuParser) parseMessage(depth int, inPlural bool) *icuError {
+	for !p.eof() {
+		switch p.src[p.pos] {
+		case '\'':
+			p.skipQuoted(inPlural)
+		case '{':
+			if p.skipMustache() {
+				continue
+			}
+			if err := p.parseArgument(inPlural); err != nil {
+				return err
+			}
+		case '}':
+			if depth == 0 {
+				return p.errorf(p.pos, "Unmatched '}'")
+			}
+			return nil
+		default:
+			p.pos++
+		}
+	}
+	return nil
+}
+
+func (p *icuParser) parseArgument(inPlural bool) *icuError {
+	start := p.pos
+	p.pos++ // '{'
+	p.skipSpace()
+
+	nameStart := p.pos
+	name := p.readIdentifier()
+	if name == "" {
+		if p.eof() {
+			return p.errorf(start, "Unclosed argument")
+		}
+		return p.errorf(nameStart, "Expected argument name")
+	}
+	p.args = append(p.args, name)
+	p.skipSpace()
+
+	switch p.peek() {
+	case '}':
+		p.pos++
+		return nil
+	case ',':
+		p.pos++
+	case 0:
+		return p.errorf(start, "Unclosed argument")
+	default:
+		return p.errorf(p.pos, "Expected ',' or '}' after argument name")
+	}
+
+	p.skipSpace()
+	typeStart := p.pos
+	argType := p.readIdentifier()
+	if argType == "" {
+		if p.eof() {
+			return p.errorf(start, "Unclosed argument")
+		}
+		return p.errorf(typeStart, "Expected argument type")
+	}
+	p.skipSpace()
+
+	switch {
+	case argType == "plural" || argType == "selectordinal":
+		if err := p.expectComma(start); err != nil {
+			return err
+		}
+		return p.parseOptions(start, true, true)
+	case argType == "select":
+		if err := p.expectComma(start); err != nil {
+			return err
+		}
+		return p.parseOptions(start, false, inPlural)
+	case icuSimpleTypes[argType]:
+		switch p.peek() {
+		case '}':
+			p.pos++
+			return nil
+		case ',':
+			p.pos++
+			return p.parseStyle(start)
+		case 0:
+			return p.errorf(start, "Unclosed argument")
+		}
+		return p.errorf(p.pos, "Expected ',' or '}' after argument type")
+	}
+
+	return p.errorf(typeStart, "Unknown argument type '%s'", argType)
+}
+
+func (p *icuParser) expectComma(start int) *icuError {
+	switch p.peek() {
+	case ',':
+		p.pos++
+		return nil
+	case 0:
+		return p.errorf(start, "Unclosed argument")
+	}
+	return p.errorf(p.pos, "Expected ','")
+}
+
+// parseStyle consumes a simple argument style such as "short" or
+// "::currency/USD" up to the closing '}'
+func (p *icuParser) parseStyle(start int) *icuError {
+	p.skipSpace()
+	styleStart := p.pos
+	nesting := 0
+	for !p.eof() {
+		switch p.src[p.pos] {
+		case '\'':
+			p.skipQuoted(false)
+			continue
+		case '{':
+			nesting++
+		case '}':
+			if nesting == 0 {
+				if p.pos == styleStart {
+					return p.errorf(p.pos, "Expected argument style")
+				}
+				p.pos++
+				return nil
+			}
+			nesting--
+		}
+		p.pos++
+	}
+	return p.errorf(start, "Unclosed argument")
+}
+
+var icuPluralKeywords = map[string]bool{
+	"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true,
+}
+
+// parseOptions consumes the selector/message pairs of a plural or select
+// argument, including the closing '}'
+func (p *icuParser) parseOptions(start int, plural, inPlural bool) *icuError {
+	p.skipSpace()
+	if plural && p.pos+7 <= len(p.src) && string(p.src[p.pos:p.pos+7]) == "offset:" {
+		p.pos += 7
+		p.skipSpace()
+		digitsStart := p.pos
+		for !p.eof() && unicode.IsDigit(p.src[p.pos]) {
+			p.pos++
+		}
+		if p.pos == digitsStart {
+			return p.errorf(p.pos, "Expected number after 'offset:'")
+		}
+	}
+
+	seen := make(map[string]bool)
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return p.errorf(start, "Unclosed argument")
+		}
+		if p.peek() == '}' {
+			break
+		}
+
+		selectorStart := p.pos
+		var selector string
+		if plural && p.peek() == '=' {
+			p.pos++
+			digitsStart := p.pos
+			for !p.eof() && unicode.IsDigit(p.src[p.pos]) {
+				p.pos++
+			}
+			if p.pos == digitsStart {
+				return p.errorf(p.pos, "Expected number after '='")
+			}
+			selector = string(p.src[selectorStart:p.pos])
+		} else {
+			selector = p.readIdentifier()
+			if selector == "" {
+				return p.errorf(selectorStart, "Expected selector")
+			}
+			if plural && !icuPluralKeywords[selector] {
+				return p.errorf(selectorStart, "Invalid plural selector '%s'", selector)
+			}
+		}
+		if seen[selector] {
+			return p.errorf(selectorStart, "Duplicate selector '%s'", selector)
+		}
+		seen[selector] = true
+
+		p.skipSpace()
+		if p.peek() != '{' {
+			if p.eof() {
+				return p.errorf(start, "Unclosed argument")
+			}
+			return p.errorf(p.pos, "Expected '{' after selector '%s'", selector)
+		}
+		open := p.pos
+		p.pos++
+		if err := p.parseMessage(1, inPlural); err != nil {
+			return err
+		}
+		if p.eof() {
+			return p.errorf(open, "Unclosed message for selector '%s'", selector)
+		}
+		p.pos++ // '}'
+	}
+
+	if len(seen) == 0 {
+		return p.errorf(p.pos, "Expected at least one selector")
+	}
+	if !seen["other"] {
+		return p.errorf(start, "Missing 'other' selector")
+	}
+	p.pos++ // closing '}' of the argument
+	return nil
+}
+
+// icuValidationEnabled reports whether a project validates values as ICU
+// MessageFormat on save
+func icuValidationEnabled(db *pgxpool.Pool, projectID string) bool {
+	enabled := true
+	_ = db.QueryRow(context.Background(),
+		`SELECT icu_validation FROM projects WHERE id = $1`, projectID,
+	).Scan(&enabled)
+	return enabled
+}
+
+// validateICUForms checks a plain value or every plural form of a cell and
+// appends one error per malformed message
+func validateICUForms(errs []models.TranslationValidationError, cell models.TranslationValidationError, value string, forms map[string]string) []models.TranslationValidationError {
+	if forms == nil {
+		if err := validateICU(value); err != nil {
+			cell.Offset, cell.Message = err.Offset, err.Message
+			errs = append(errs, cell)
+		}
+		return errs
+	}
+	for _, category := range pluralCategories {
+		if err := validateICU(forms[category]); err != nil {
+			c := cell
+			c.Category, c.Offset, c.Message = category, err.Offset, err.Message
+			errs = append(errs, c)
+		}
+	}
+	return errs
+}
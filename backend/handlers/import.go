@@ -3,6 +3,8 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"sort"
 	"strings"
 
 	"translate-management/models"
@@ -19,7 +21,7 @@ func NewImportHandler(db *pgxpool.Pool) *ImportHandler {
 	return &ImportHandler{DB: db}
 }
 
-// Import imports translation JSON data into a project
+// Import imports translation JSON, XLIFF or gettext PO data into a project
 func (h *ImportHandler) Import(c *fiber.Ctx) error {
 	projectID := c.Params("id")
 	userID := c.Locals("user_id").(string)
@@ -35,24 +37,46 @@ func (h *ImportHandler) Import(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
-	if req.LanguageCode == "" || req.Translations == nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Language code and translations are required"})
+	entries, langCode, err := parseImportRequest(&req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if langCode == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Language code is required"})
 	}
 
+	return h.importEntries(c, projectID, userID, langCode, entries)
+}
+
+// importEntries writes parsed import entries into a language, creating
+// missing keys, and responds with the import summary. userID is empty for
+// imports made with an API key.
+func (h *ImportHandler) importEntries(c *fiber.Ctx, projectID, userID, langCode string, entries []importEntry) error {
 	// Get or create language
 	var langID string
 	err := h.DB.QueryRow(context.Background(),
 		`SELECT id FROM languages WHERE project_id = $1 AND code = $2`,
-		projectID, req.LanguageCode,
+		projectID, langCode,
 	).Scan(&langID)
 
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Language not found. Create it first."})
 	}
 
-	// Flatten nested JSON
-	flat := make(map[string]string)
-	flattenJSON("", req.Translations, flat)
+	// Formats without native plurals carry plural forms as "<key>.<category>"
+	pluralKeys, err := h.pluralKeys(projectID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch keys"})
+	}
+	entries = mergePluralEntries(entries, pluralKeys)
+
+	// Reject malformed ICU messages before anything is written
+	if icuValidationEnabled(h.DB, projectID) {
+		if invalid := h.validateICUEntries(projectID, langID, entries); len(invalid) > 0 {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Invalid ICU message format", "errors": invalid})
+		}
+	}
 
 	tx, err := h.DB.Begin(context.Background())
 	if err != nil {
@@ -61,32 +85,59 @@ func (h *ImportHandler) Import(c *fiber.Ctx) error {
 	defer tx.Rollback(context.Background())
 
 	imported := 0
-	for key, value := range flat {
-		// Upsert key
+	importedKeys := []string{}
+	for _, entry := range entries {
+		// Upsert key, keeping the existing description unless the file carries one
 		var keyID string
 		err := tx.QueryRow(context.Background(),
-			`INSERT INTO translation_keys (project_id, key) 
-			 VALUES ($1, $2) 
-			 ON CONFLICT (project_id, key) DO UPDATE SET updated_at = NOW()
+			`INSERT INTO translation_keys (project_id, key, description, is_plural) 
+			 VALUES ($1, $2, $3, $4) 
+			 ON CONFLICT (project_id, key) DO UPDATE SET
+			 	description = CASE WHEN EXCLUDED.description <> '' THEN EXCLUDED.description ELSE translation_keys.description END,
+			 	is_plural = translation_keys.is_plural OR EXCLUDED.is_plural,
+			 	updated_at = NOW()
 			 RETURNING id`,
-			projectID, key,
+			projectID, entry.Key, entry.Description, entry.Forms != nil,
 		).Scan(&keyID)
 
 		if err != nil {
 			continue
 		}
 
+		old, err := readTranslationCell(tx, keyID, langID, "")
+		if err != nil {
+			continue
+		}
+
 		// Upsert translation
-		_, err = tx.Exec(context.Background(),
-			`INSERT INTO translations (key_id, language_id, value, updated_by) 
-			 VALUES ($1, $2, $3, $4) 
-			 ON CONFLICT (key_id, language_id) 
-			 DO UPDATE SET value = EXCLUDED.value, updated_at = NOW(), updated_by = EXCLUDED.updated_by`,
-			keyID, langID, value, userID,
-		)
+		if entry.Forms != nil {
+			// Merge imported plural forms over the stored ones
+			_, err = tx.Exec(context.Background(),
+				`INSERT INTO translations (key_id, language_id, value, plural_forms, updated_by) 
+				 VALUES ($1, $2, $3, $4, $5) 
+				 ON CONFLICT (key_id, language_id) 
+				 DO UPDATE SET
+				 	plural_forms = COALESCE(translations.plural_forms, '{}'::jsonb) || EXCLUDED.plural_forms,
+				 	value = COALESCE((COALESCE(translations.plural_forms, '{}'::jsonb) || EXCLUDED.plural_forms)->>'other', ''),
+				 	updated_at = NOW(), updated_by = EXCLUDED.updated_by`,
+				keyID, langID, entry.Forms["other"], entry.Forms, nullableUser(userID),
+			)
+		} else {
+			_, err = tx.Exec(context.Background(),
+				`INSERT INTO translations (key_id, language_id, value, updated_by) 
+				 VALUES ($1, $2, $3, $4) 
+				 ON CONFLICT (key_id, language_id) 
+				 DO UPDATE SET value = EXCLUDED.value, updated_at = NOW(), updated_by = EXCLUDED.updated_by`,
+				keyID, langID, entry.Value, nullableUser(userID),
+			)
+		}
 
+		if err == nil {
+			err = recordHistory(tx, keyID, langID, "", userID, "import", old, nil)
+		}
 		if err == nil {
 			imported++
+			importedKeys = append(importedKeys, keyID)
 		}
 	}
 
@@ -94,12 +145,167 @@ func (h *ImportHandler) Import(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit import"})
 	}
 
+	// Flag imported values that miss approved glossary terms
+	glossary := []models.GlossaryIssue{}
+	if terms, err := loadGlossary(h.DB, projectID, ""); err == nil && len(terms) > 0 && len(importedKeys) > 0 {
+		if _, cells, err := loadQACells(h.DB, projectID, langCode, importedKeys); err == nil {
+			glossary = findGlossaryIssues(cells, terms)
+		}
+	}
+
 	return c.JSON(fiber.Map{
 		"message":  "Import completed",
 		"imported": imported,
+		"glossary": glossary,
 	})
 }
 
+// importEntry is a single key/value pair read from an import file
+type importEntry struct {
+	Key         string
+	Value       string
+	Description string
+	Forms       map[string]string // plural category -> value, nil for plain keys
+}
+
+// pluralKeys returns the set of plural keys in a project
+func (h *ImportHandler) pluralKeys(projectID string) (map[string]bool, error) {
+	rows, err := h.DB.Query(context.Background(),
+		`SELECT key FROM translation_keys WHERE project_id = $1 AND is_plural = TRUE`, projectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err == nil {
+			keys[key] = true
+		}
+	}
+	return keys, rows.Err()
+}
+
+// validateICUEntries checks every imported value and reports errors against
+// the key name and, for keys that already exist, the key ID
+func (h *ImportHandler) validateICUEntries(projectID, langID string, entries []importEntry) []models.TranslationValidationError {
+	invalid := []models.TranslationValidationError{}
+	for _, e := range entries {
+		cell := models.TranslationValidationError{Key: e.Key, LanguageID: langID}
+		invalid = validateICUForms(invalid, cell, e.Value, e.Forms)
+	}
+	if len(invalid) == 0 {
+		return invalid
+	}
+
+	keys := make([]string, 0, len(invalid))
+	for _, e := range invalid {
+		keys = append(keys, e.Key)
+	}
+	rows, err := h.DB.Query(context.Background(),
+		`SELECT key, id FROM translation_keys WHERE project_id = $1 AND key = ANY($2)`, projectID, keys,
+	)
+	if err != nil {
+		return invalid
+	}
+	defer rows.Close()
+
+	keyIDs := make(map[string]string)
+	for rows.Next() {
+		var key, id string
+		if err := rows.Scan(&key, &id); err == nil {
+			keyIDs[key] = id
+		}
+	}
+	for i := range invalid {
+		invalid[i].KeyID = keyIDs[invalid[i].Key]
+	}
+	return invalid
+}
+
+// mergePluralEntries folds "<key>.<category>" entries into a single plural
+// entry when <key> is a plural key of the project
+func mergePluralEntries(entries []importEntry, pluralKeys map[string]bool) []importEntry {
+	merged := make([]importEntry, 0, len(entries))
+	index := make(map[string]int)
+	for _, e := range entries {
+		base, category, ok := splitPluralKey(e.Key)
+		if e.Forms != nil || !ok || !pluralKeys[base] {
+			merged = append(merged, e)
+			continue
+		}
+		i, seen := index[base]
+		if !seen {
+			i = len(merged)
+			index[base] = i
+			merged = append(merged, importEntry{Key: base, Description: e.Description, Forms: map[string]string{}})
+		}
+		merged[i].Forms[category] = e.Value
+	}
+	return merged
+}
+
+// parseImportRequest decodes the request payload according to its format and
+// returns the entries along with the target language code. File formats may
+// carry their own target language, which is used when language_code is empty.
+func parseImportRequest(req *models.ImportRequest) ([]importEntry, string, error) {
+	format := req.Format
+	if format == "" {
+		format = "json"
+	}
+
+	switch format {
+	case "json":
+		if req.Translations == nil {
+			return nil, "", errors.New("Language code and translations are required")
+		}
+		flat := make(map[string]string)
+		flattenJSON("", req.Translations, flat)
+
+		keys := make([]string, 0, len(flat))
+		for k := range flat {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		entries := make([]importEntry, 0, len(keys))
+		for _, k := range keys {
+			entries = append(entries, importEntry{Key: k, Value: flat[k]})
+		}
+		return entries, req.LanguageCode, nil
+	case "xliff12", "xliff20":
+		if req.Content == "" {
+			return nil, "", errors.New("Content is required for XLIFF imports")
+		}
+		fileLang, entries, err := parseXLIFF([]byte(req.Content))
+		if err != nil {
+			return nil, "", err
+		}
+		langCode := req.LanguageCode
+		if langCode == "" {
+			langCode = fileLang
+		}
+		return entries, langCode, nil
+	case "po":
+		if req.Content == "" {
+			return nil, "", errors.New("Content is required for PO imports")
+		}
+		fileLang, messages, err := parsePO([]byte(req.Content))
+		if err != nil {
+			return nil, "", err
+		}
+		langCode := req.LanguageCode
+		if langCode == "" {
+			langCode = fileLang
+		}
+		return poImportEntries(messages, langCode), langCode, nil
+	}
+
+	return nil, "", errors.New("Format must be one of: json, xliff12, xliff20, po")
+}
+
 // flattenJSON converts nested maps to dot-notation flat keys
 func flattenJSON(prefix string, data map[string]interface{}, result map[string]string) {
 	for key, value := range data {
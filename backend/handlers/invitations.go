@@ -38,6 +38,11 @@ func (h *InvitationHandler) InviteUser(c *fiber.Ctx) error {
 	if req.Role == "" {
 		req.Role = "viewer"
 	}
+	switch req.Role {
+	case "owner", "editor", "reviewer", "viewer":
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Role must be one of: owner, editor, reviewer, viewer"})
+	}
 
 	// Verify project ownership
 	var projectExists bool
@@ -153,6 +158,13 @@ func (h *InvitationHandler) RespondToInvitation(c *fiber.Ctx) error {
 
 	newStatus := "rejected"
 	if req.Accept {
+		// Invitations are matched by email, so the address must be proven
+		var verified bool
+		if err := tx.QueryRow(context.Background(),
+			`SELECT email_verified FROM users WHERE id = $1`, userID).Scan(&verified); err != nil || !verified {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Verify your email address before accepting invitations"})
+		}
+
 		newStatus = "accepted"
 		// Add to project members
 		_, err := tx.Exec(context.Background(),
@@ -38,7 +38,7 @@ func (h *KeyHandler) List(c *fiber.Ctx) error {
 	}
 	search := c.Query("search", "")
 
-	query := `SELECT id, project_id, key, description, created_at, updated_at 
+	query := `SELECT id, project_id, key, description, is_plural, created_at, updated_at 
 			  FROM translation_keys WHERE project_id = $1`
 	args := []interface{}{projectID}
 
@@ -57,7 +57,7 @@ func (h *KeyHandler) List(c *fiber.Ctx) error {
 	keys := []models.TranslationKey{}
 	for rows.Next() {
 		var k models.TranslationKey
-		if err := rows.Scan(&k.ID, &k.ProjectID, &k.Key, &k.Description, &k.CreatedAt, &k.UpdatedAt); err != nil {
+		if err := rows.Scan(&k.ID, &k.ProjectID, &k.Key, &k.Description, &k.IsPlural, &k.CreatedAt, &k.UpdatedAt); err != nil {
 			continue
 		}
 		keys = append(keys, k)
@@ -103,11 +103,11 @@ func (h *KeyHandler) Create(c *fiber.Ctx) error {
 
 	var k models.TranslationKey
 	err = h.DB.QueryRow(context.Background(),
-		`INSERT INTO translation_keys (project_id, key, description) 
-		 VALUES ($1, $2, $3) 
-		 RETURNING id, project_id, key, description, created_at, updated_at`,
-		projectID, req.Key, req.Description,
-	).Scan(&k.ID, &k.ProjectID, &k.Key, &k.Description, &k.CreatedAt, &k.UpdatedAt)
+		`INSERT INTO translation_keys (project_id, key, description, is_plural) 
+		 VALUES ($1, $2, $3, $4) 
+		 RETURNING id, project_id, key, description, is_plural, created_at, updated_at`,
+		projectID, req.Key, req.Description, req.IsPlural,
+	).Scan(&k.ID, &k.ProjectID, &k.Key, &k.Description, &k.IsPlural, &k.CreatedAt, &k.UpdatedAt)
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create key. Key might already exist."})
@@ -157,11 +157,11 @@ func (h *KeyHandler) Update(c *fiber.Ctx) error {
 
 	var k models.TranslationKey
 	err = h.DB.QueryRow(context.Background(),
-		`UPDATE translation_keys SET key = $1, description = $2, updated_at = NOW() 
+		`UPDATE translation_keys SET key = $1, description = $2, is_plural = COALESCE($5, is_plural), updated_at = NOW() 
 		 WHERE id = $3 AND project_id = $4 
-		 RETURNING id, project_id, key, description, created_at, updated_at`,
-		req.Key, req.Description, keyID, projectID,
-	).Scan(&k.ID, &k.ProjectID, &k.Key, &k.Description, &k.CreatedAt, &k.UpdatedAt)
+		 RETURNING id, project_id, key, description, is_plural, created_at, updated_at`,
+		req.Key, req.Description, keyID, projectID, req.IsPlural,
+	).Scan(&k.ID, &k.ProjectID, &k.Key, &k.Description, &k.IsPlural, &k.CreatedAt, &k.UpdatedAt)
 
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Key not found"})
@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"translate-management/cache"
 	"translate-management/models"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -38,7 +41,7 @@ func (h *LanguageHandler) List(c *fiber.Ctx) error {
 	}
 
 	rows, err := h.DB.Query(context.Background(),
-		`SELECT id, project_id, code, name, is_default, created_at 
+		`SELECT id, project_id, code, name, is_default, fallbacks, created_at 
 		 FROM languages WHERE project_id = $1 ORDER BY is_default DESC, name ASC`,
 		projectID,
 	)
@@ -50,9 +53,10 @@ func (h *LanguageHandler) List(c *fiber.Ctx) error {
 	languages := []models.Language{}
 	for rows.Next() {
 		var l models.Language
-		if err := rows.Scan(&l.ID, &l.ProjectID, &l.Code, &l.Name, &l.IsDefault, &l.CreatedAt); err != nil {
+		if err := rows.Scan(&l.ID, &l.ProjectID, &l.Code, &l.Name, &l.IsDefault, &l.Fallbacks, &l.CreatedAt); err != nil {
 			continue
 		}
+		l.PluralCategories = pluralRuleFor(l.Code).Categories
 		languages = append(languages, l)
 	}
 
@@ -94,6 +98,13 @@ func (h *LanguageHandler) Create(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Code and name are required"})
 	}
 
+	if req.Fallbacks == nil {
+		req.Fallbacks = []string{}
+	}
+	if err := h.validateFallbacks(projectID, req.Code, req.Fallbacks); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	// If setting as default, unset other defaults first
 	if req.IsDefault {
 		_, _ = h.DB.Exec(context.Background(),
@@ -103,15 +114,16 @@ func (h *LanguageHandler) Create(c *fiber.Ctx) error {
 
 	var l models.Language
 	err = h.DB.QueryRow(context.Background(),
-		`INSERT INTO languages (project_id, code, name, is_default) 
-		 VALUES ($1, $2, $3, $4) 
-		 RETURNING id, project_id, code, name, is_default, created_at`,
-		projectID, req.Code, req.Name, req.IsDefault,
-	).Scan(&l.ID, &l.ProjectID, &l.Code, &l.Name, &l.IsDefault, &l.CreatedAt)
+		`INSERT INTO languages (project_id, code, name, is_default, fallbacks) 
+		 VALUES ($1, $2, $3, $4, $5) 
+		 RETURNING id, project_id, code, name, is_default, fallbacks, created_at`,
+		projectID, req.Code, req.Name, req.IsDefault, req.Fallbacks,
+	).Scan(&l.ID, &l.ProjectID, &l.Code, &l.Name, &l.IsDefault, &l.Fallbacks, &l.CreatedAt)
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create language. Code might already exist."})
 	}
+	l.PluralCategories = pluralRuleFor(l.Code).Categories
 
 	h.invalidateCache(projectID)
 
@@ -150,6 +162,18 @@ func (h *LanguageHandler) Update(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
+	if req.Fallbacks != nil {
+		var code string
+		if err := h.DB.QueryRow(context.Background(),
+			`SELECT code FROM languages WHERE id = $1 AND project_id = $2`, langID, projectID,
+		).Scan(&code); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Language not found"})
+		}
+		if err := h.validateFallbacks(projectID, code, req.Fallbacks); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
 	if req.IsDefault {
 		_, _ = h.DB.Exec(context.Background(),
 			`UPDATE languages SET is_default = FALSE WHERE project_id = $1`, projectID,
@@ -158,15 +182,16 @@ func (h *LanguageHandler) Update(c *fiber.Ctx) error {
 
 	var l models.Language
 	err = h.DB.QueryRow(context.Background(),
-		`UPDATE languages SET name = $1, is_default = $2 
+		`UPDATE languages SET name = $1, is_default = $2, fallbacks = COALESCE($5, fallbacks) 
 		 WHERE id = $3 AND project_id = $4 
-		 RETURNING id, project_id, code, name, is_default, created_at`,
-		req.Name, req.IsDefault, langID, projectID,
-	).Scan(&l.ID, &l.ProjectID, &l.Code, &l.Name, &l.IsDefault, &l.CreatedAt)
+		 RETURNING id, project_id, code, name, is_default, fallbacks, created_at`,
+		req.Name, req.IsDefault, langID, projectID, req.Fallbacks,
+	).Scan(&l.ID, &l.ProjectID, &l.Code, &l.Name, &l.IsDefault, &l.Fallbacks, &l.CreatedAt)
 
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Language not found"})
 	}
+	l.PluralCategories = pluralRuleFor(l.Code).Categories
 
 	h.invalidateCache(projectID)
 
@@ -200,22 +225,56 @@ func (h *LanguageHandler) Delete(c *fiber.Ctx) error {
 	}
 	langID := c.Params("langId")
 
-	result, err := h.DB.Exec(context.Background(),
-		`DELETE FROM languages WHERE id = $1 AND project_id = $2`, langID, projectID,
-	)
+	var code string
+	err = h.DB.QueryRow(context.Background(),
+		`DELETE FROM languages WHERE id = $1 AND project_id = $2 RETURNING code`, langID, projectID,
+	).Scan(&code)
+	if err == pgx.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Language not found"})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete language"})
 	}
 
-	if result.RowsAffected() == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Language not found"})
-	}
+	// Drop the deleted language from other languages' fallback chains
+	_, _ = h.DB.Exec(context.Background(),
+		`UPDATE languages SET fallbacks = array_remove(fallbacks, $2) WHERE project_id = $1`, projectID, code,
+	)
 
 	h.invalidateCache(projectID)
 
 	return c.JSON(fiber.Map{"message": "Language deleted"})
 }
 
+// validateFallbacks checks that a fallback chain only names other languages
+// of the project, each at most once
+func (h *LanguageHandler) validateFallbacks(projectID, code string, fallbacks []string) error {
+	if len(fallbacks) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for _, f := range fallbacks {
+		if f == code {
+			return errors.New("A language cannot fall back to itself")
+		}
+		if seen[f] {
+			return fmt.Errorf("Fallback language '%s' is listed twice", f)
+		}
+		seen[f] = true
+	}
+
+	var found int
+	if err := h.DB.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM languages WHERE project_id = $1 AND code = ANY($2)`, projectID, fallbacks,
+	).Scan(&found); err != nil {
+		return errors.New("Failed to check fallback languages")
+	}
+	if found != len(fallbacks) {
+		return errors.New("Fallback languages must exist in the project")
+	}
+	return nil
+}
+
 func (h *LanguageHandler) invalidateCache(projectID string) {
 	var slug string
 	_ = h.DB.QueryRow(context.Background(), "SELECT slug FROM projects WHERE id = $1", projectID).Scan(&slug)
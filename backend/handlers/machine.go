@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"translate-management/cache"
+	"translate-management/models"
+	"translate-management/translator"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// machineBatchSize is the number of texts sent to a provider per request
+const machineBatchSize = 50
+
+type MachineTranslationHandler struct {
+	DB    *pgxpool.Pool
+	Cache *cache.RedisClient
+}
+
+func NewMachineTranslationHandler(db *pgxpool.Pool, rdb *cache.RedisClient) *MachineTranslationHandler {
+	return &MachineTranslationHandler{DB: db, Cache: rdb}
+}
+
+// projectRole returns the user's role in a project, or "" with a 404 written
+// when the project is not found or the user is not a member
+func (h *MachineTranslationHandler) projectRole(c *fiber.Ctx, projectID, userID string) (string, error) {
+	var role string
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT
+			CASE
+				WHEN p.created_by = $2 THEN 'owner'
+				ELSE COALESCE(pm.role, '')
+			END as role
+		FROM projects p
+		LEFT JOIN project_members pm ON p.id = pm.project_id AND pm.user_id = $2
+		WHERE p.id = $1 AND (p.created_by = $2 OR pm.user_id = $2)`,
+		projectID, userID).Scan(&role)
+	if err != nil {
+		c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or access denied"})
+		return "", err
+	}
+	return role, nil
+}
+
+// GetSettings returns the project's machine-translation provider
+func (h *MachineTranslationHandler) GetSettings(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	if _, err := h.projectRole(c, projectID, userID); err != nil {
+		return err
+	}
+
+	s := models.MachineTranslationSettings{Providers: translator.Providers()}
+	var updatedAt time.Time
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT provider, endpoint, api_key <> '', updated_at FROM machine_translation_settings WHERE project_id = $1`,
+		projectID,
+	).Scan(&s.Provider, &s.Endpoint, &s.HasAPIKey, &updatedAt)
+	if err == nil {
+		s.UpdatedAt = &updatedAt
+	} else if err != pgx.ErrNoRows {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch settings"})
+	}
+
+	return c.JSON(s)
+}
+
+// UpdateSettings configures the project's machine-translation provider (owner only)
+func (h *MachineTranslationHandler) UpdateSettings(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	role, err := h.projectRole(c, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if role != "owner" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only project owners can configure machine translation"})
+	}
+
+	var req models.UpdateMachineTranslationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if req.Provider == "" {
+		if _, err := h.DB.Exec(context.Background(),
+			`DELETE FROM machine_translation_settings WHERE project_id = $1`, projectID,
+		); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update settings"})
+		}
+		return c.JSON(models.MachineTranslationSettings{Providers: translator.Providers()})
+	}
+
+	// Building the provider validates its configuration
+	if _, err := translator.New(req.Provider, translator.Config{Endpoint: req.Endpoint}); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	s := models.MachineTranslationSettings{Providers: translator.Providers()}
+	var updatedAt time.Time
+	err = h.DB.QueryRow(context.Background(),
+		`INSERT INTO machine_translation_settings (project_id, provider, endpoint, api_key, updated_by)
+		 VALUES ($1, $2, $3, COALESCE($4, ''), $5)
+		 ON CONFLICT (project_id) DO UPDATE SET
+		 	provider = EXCLUDED.provider,
+		 	endpoint = EXCLUDED.endpoint,
+		 	api_key = COALESCE($4, machine_translation_settings.api_key),
+		 	updated_by = EXCLUDED.updated_by,
+		 	updated_at = NOW()
+		 RETURNING provider, endpoint, api_key <> '', updated_at`,
+		projectID, req.Provider, req.Endpoint, req.APIKey, userID,
+	).Scan(&s.Provider, &s.Endpoint, &s.HasAPIKey, &updatedAt)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update settings"})
+	}
+	s.UpdatedAt = &updatedAt
+
+	return c.JSON(s)
+}
+
+// machineCell is an empty translation with a default-language value to translate
+type machineCell struct {
+	KeyID  string
+	Key    string
+	Source string
+}
+
+// Translate pre-fills empty cells of a language by machine-translating the
+// default-language values. Cells with a value are never overwritten; the new
+// values are drafts marked as machine-translated. Plural keys are skipped.
+func (h *MachineTranslationHandler) Translate(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	role, err := h.projectRole(c, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if role != "owner" && role != "editor" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	var req models.MachineTranslateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.LanguageID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Language ID is required"})
+	}
+
+	var provider string
+	var cfg translator.Config
+	err = h.DB.QueryRow(context.Background(),
+		`SELECT provider, endpoint, api_key FROM machine_translation_settings WHERE project_id = $1`, projectID,
+	).Scan(&provider, &cfg.Endpoint, &cfg.APIKey)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Machine translation is not configured for this project"})
+	}
+	mt, err := translator.New(provider, cfg)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var targetCode string
+	var targetIsDefault bool
+	err = h.DB.QueryRow(context.Background(),
+		`SELECT code, is_default FROM languages WHERE id = $1 AND project_id = $2`, req.LanguageID, projectID,
+	).Scan(&targetCode, &targetIsDefault)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Language not found"})
+	}
+	if targetIsDefault {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot machine-translate the default language"})
+	}
+
+	var sourceID, sourceCode string
+	err = h.DB.QueryRow(context.Background(),
+		`SELECT id, code FROM languages WHERE project_id = $1 AND is_default = TRUE LIMIT 1`, projectID,
+	).Scan(&sourceID, &sourceCode)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Project has no default language"})
+	}
+
+	cells, skipped, err := h.emptyCells(projectID, sourceID, req.LanguageID, req.KeyIDs)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch translations"})
+	}
+
+	// Translate everything before writing, so a provider failure changes nothing
+	values := make([]string, 0, len(cells))
+	for start := 0; start < len(cells); start += machineBatchSize {
+		end := min(start+machineBatchSize, len(cells))
+		texts := make([]string, 0, end-start)
+		for _, cell := range cells[start:end] {
+			texts = append(texts, cell.Source)
+		}
+		out, err := mt.Translate(c.Context(), texts, sourceCode, targetCode)
+		if err != nil {
+			// The endpoint is set by project owners; its errors can describe
+			// hosts the server reaches, so they stay in the log
+			log.Printf("Machine translation for project %s failed: %v", projectID, err)
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Machine translation failed, check the provider settings"})
+		}
+		values = append(values, out...)
+	}
+
+	validateICUValues := icuValidationEnabled(h.DB, projectID)
+	terms, _ := loadGlossary(h.DB, projectID, "")
+	warnings := []models.PlaceholderIssue{}
+	glossary := []models.GlossaryIssue{}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	translated := 0
+	for i, cell := range cells {
+		value := values[i]
+		skip := func(reason string) {
+			skipped = append(skipped, models.SkippedTranslation{KeyID: cell.KeyID, Key: cell.Key, Reason: reason})
+		}
+		if value == "" {
+			skip("Provider returned an empty translation")
+			continue
+		}
+		if validateICUValues && validateICU(value) != nil {
+			skip("Machine translation is not a valid ICU message")
+			continue
+		}
+
+		old, err := readTranslationCell(tx, cell.KeyID, req.LanguageID, "")
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read translation"})
+		}
+		if old != nil && old.Value != "" {
+			skip("Translation was filled in meanwhile")
+			continue
+		}
+
+		t := models.TranslationUpdate{KeyID: cell.KeyID, LanguageID: req.LanguageID, Value: value}
+		if err := writeTranslation(tx, "", userID, t); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update translation"})
+		}
+		if err := recordHistory(tx, cell.KeyID, req.LanguageID, "", userID, "machine", old, nil); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record history"})
+		}
+		issue := models.PlaceholderIssue{KeyID: cell.KeyID, Key: cell.Key, LanguageID: req.LanguageID}
+		warnings = checkPlaceholderCell(warnings, issue, cell.Source, value, nil, nil)
+		term := models.GlossaryIssue{KeyID: cell.KeyID, Key: cell.Key, LanguageID: req.LanguageID}
+		glossary = checkGlossaryCell(glossary, term, terms, cell.Source, value, nil, nil)
+		translated++
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
+
+	if translated > 0 {
+		var slug string
+		_ = h.DB.QueryRow(context.Background(), "SELECT slug FROM projects WHERE id = $1", projectID).Scan(&slug)
+		if slug != "" {
+			_ = h.Cache.DeleteByPattern(context.Background(), cache.ProjectCachePattern(slug))
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message":    "Machine translation completed",
+		"provider":   provider,
+		"translated": translated,
+		"skipped":    skipped,
+		"warnings":   warnings,
+		"glossary":   glossary,
+	})
+}
+
+// emptyCells returns the keys that have a default-language value but no value
+// in the target language, optionally limited to keyIDs. Plural keys are
+// returned as skipped.
+func (h *MachineTranslationHandler) emptyCells(projectID, sourceID, targetID string, keyIDs []string) ([]machineCell, []models.SkippedTranslation, error) {
+	query := `SELECT tk.id, tk.key, tk.is_plural, s.value
+		 FROM translation_keys tk
+		 JOIN translations s ON s.key_id = tk.id AND s.language_id = $2
+		 LEFT JOIN translations t ON t.key_id = tk.id AND t.language_id = $3
+		 WHERE tk.project_id = $1 AND s.value <> '' AND COALESCE(t.value, '') = ''`
+	args := []interface{}{projectID, sourceID, targetID}
+	if len(keyIDs) > 0 {
+		args = append(args, keyIDs)
+		query += ` AND tk.id = ANY($4)`
+	}
+	query += ` ORDER BY tk.key ASC`
+
+	rows, err := h.DB.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	cells := []machineCell{}
+	skipped := []models.SkippedTranslation{}
+	for rows.Next() {
+		var cell machineCell
+		var isPlural bool
+		if err := rows.Scan(&cell.KeyID, &cell.Key, &isPlural, &cell.Source); err != nil {
+			continue
+		}
+		if isPlural {
+			skipped = append(skipped, models.SkippedTranslation{KeyID: cell.KeyID, Key: cell.Key, Reason: "Plural keys are not machine-translated"})
+			continue
+		}
+		cells = append(cells, cell)
+	}
+	return cells, skipped, rows.Err()
+}
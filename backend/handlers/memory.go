@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+
+	"translate-management/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// minMemoryScore is the lowest similarity the trigram index can match
+// (pg_trgm's default similarity_threshold)
+const minMemoryScore = 0.3
+
+type MemoryHandler struct {
+	DB *pgxpool.Pool
+}
+
+func NewMemoryHandler(db *pgxpool.Pool) *MemoryHandler {
+	return &MemoryHandler{DB: db}
+}
+
+// indexTranslationMemory stores the approved value of a cell in the
+// translation memory, paired with the key's current default-language value.
+// Default-language cells and empty values are not indexed.
+func indexTranslationMemory(tx pgx.Tx, keyID, languageID string) error {
+	_, err := tx.Exec(context.Background(),
+		`INSERT INTO translation_memory (project_id, key_id, source_language, target_language, source_text, target_text)
+		 SELECT tk.project_id, tk.id, sl.code, l.code, s.value, t.approved_value
+		 FROM translations t
+		 JOIN translation_keys tk ON tk.id = t.key_id
+		 JOIN languages l ON l.id = t.language_id AND NOT l.is_default
+		 JOIN languages sl ON sl.project_id = tk.project_id AND sl.is_default
+		 JOIN translations s ON s.key_id = t.key_id AND s.language_id = sl.id
+		 WHERE t.key_id = $1 AND t.language_id = $2 AND t.approved_value <> '' AND s.value <> ''
+		 LIMIT 1
+		 ON CONFLICT (key_id, target_language) DO UPDATE SET
+		 	source_language = EXCLUDED.source_language,
+		 	source_text = EXCLUDED.source_text,
+		 	target_text = EXCLUDED.target_text,
+		 	updated_at = NOW()`,
+		keyID, languageID,
+	)
+	return err
+}
+
+// Suggestions looks up the key's default-language value in the translation
+// memory of every project the user can access (projects requiring 2FA only
+// for sessions started with a second factor) and returns the approved
+// translations into ?lang=xx, exact matches first. ?min_score (default 0.5)
+// and ?limit (default 10) tune the fuzzy matches.
+func (h *MemoryHandler) Suggestions(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	keyID := c.Params("keyId")
+	userID := c.Locals("user_id").(string)
+
+	// Verify membership (any role)
+	var exists bool
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT EXISTS(
+			SELECT 1 FROM projects p
+			LEFT JOIN project_members pm ON p.id = pm.project_id
+			WHERE p.id = $1 AND (p.created_by = $2 OR pm.user_id = $2)
+		)`, projectID, userID).Scan(&exists)
+	if err != nil || !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or access denied"})
+	}
+
+	langCode := c.Query("lang", "")
+	if langCode == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "lang is required"})
+	}
+	minScore := c.QueryFloat("min_score", 0.5)
+	if minScore < minMemoryScore {
+		minScore = minMemoryScore
+	}
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	err = h.DB.QueryRow(context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM translation_keys WHERE id = $1 AND project_id = $2)`, keyID, projectID,
+	).Scan(&exists)
+	if err != nil || !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Key not found"})
+	}
+
+	result := models.MemorySuggestions{KeyID: keyID, TargetLanguage: langCode, Suggestions: []models.MemorySuggestion{}}
+	err = h.DB.QueryRow(context.Background(),
+		`SELECT l.code, COALESCE(t.value, '')
+		 FROM languages l
+		 LEFT JOIN translations t ON t.language_id = l.id AND t.key_id = $2
+		 WHERE l.project_id = $1 AND l.is_default = TRUE
+		 LIMIT 1`,
+		projectID, keyID,
+	).Scan(&result.SourceLanguage, &result.Source)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Project has no default language"})
+	}
+	if result.Source == "" {
+		return c.JSON(result)
+	}
+
+	rows, err := h.DB.Query(context.Background(),
+		`SELECT tm.source_text, tm.target_text, similarity(tm.source_text, $1)::float8 AS score,
+			COUNT(*), array_agg(DISTINCT p.name)
+		 FROM translation_memory tm
+		 JOIN projects p ON p.id = tm.project_id
+		 WHERE tm.source_language = $2 AND tm.target_language = $3 AND tm.key_id <> $4
+		 	AND (tm.source_text = $1 OR tm.source_text % $1)
+		 	AND (p.created_by = $5 OR EXISTS(
+		 		SELECT 1 FROM project_members pm WHERE pm.project_id = p.id AND pm.user_id = $5))
+		 	AND (NOT p.require_2fa OR $8)
+		 GROUP BY tm.source_text, tm.target_text
+		 HAVING tm.source_text = $1 OR similarity(tm.source_text, $1) >= $6
+		 ORDER BY tm.source_text = $1 DESC, score DESC, COUNT(*) DESC
+		 LIMIT $7`,
+		result.Source, result.SourceLanguage, langCode, keyID, userID, minScore, limit, c.Locals("mfa").(bool),
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to search translation memory"})
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.MemorySuggestion
+		if err := rows.Scan(&s.SourceText, &s.TargetText, &s.Score, &s.Uses, &s.Projects); err != nil {
+			continue
+		}
+		s.Match = "fuzzy"
+		if s.SourceText == result.Source {
+			s.Match, s.Score = "exact", 1
+		}
+		result.Suggestions = append(result.Suggestions, s)
+	}
+
+	return c.JSON(result)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var androidNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// androidResourceName turns a translation key into a valid Android resource
+// name, e.g. "home.hero-title" -> "home_hero_title"
+func androidResourceName(key string) string {
+	name := strings.Trim(androidNameInvalid.ReplaceAllString(key, "_"), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+var androidEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	`"`, `\"`,
+	`@`, `\@`,
+	"\n", `\n`,
+	"\t", `\t`,
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+)
+
+// escapeAndroid escapes a value for use inside a strings.xml element
+func escapeAndroid(value string) string {
+	escaped := androidEscaper.Replace(value)
+	if strings.HasPrefix(escaped, "?") {
+		escaped = `\` + escaped
+	}
+	return escaped
+}
+
+func xmlComment(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "--", "- -"), "\n", " ")
+}
+
+// encodeAndroidStrings renders an export document as an Android strings.xml.
+// Untranslated keys are omitted so Android falls back to the default resources,
+// and plural keys become <plurals> resources.
+func encodeAndroidStrings(doc *exportDocument) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<resources>\n")
+
+	used := make(map[string]int)
+	uniqueName := func(key string) string {
+		name := androidResourceName(key)
+		used[name]++
+		if used[name] > 1 {
+			name = fmt.Sprintf("%s_%d", name, used[name])
+		}
+		return name
+	}
+
+	for _, e := range doc.Entries {
+		if e.IsPlural {
+			items := ""
+			for _, category := range pluralCategories {
+				if value := e.Forms[category]; value != "" {
+					items += fmt.Sprintf("        <item quantity=\"%s\">%s</item>\n", category, escapeAndroid(value))
+				}
+			}
+			if items == "" {
+				continue
+			}
+			if e.Description != "" {
+				fmt.Fprintf(&b, "    <!-- %s -->\n", xmlComment(e.Description))
+			}
+			fmt.Fprintf(&b, "    <plurals name=\"%s\">\n%s    </plurals>\n", uniqueName(e.Key), items)
+			continue
+		}
+
+		if e.Value == "" {
+			continue
+		}
+		if e.Description != "" {
+			fmt.Fprintf(&b, "    <!-- %s -->\n", xmlComment(e.Description))
+		}
+		fmt.Fprintf(&b, "    <string name=\"%s\">%s</string>\n", uniqueName(e.Key), escapeAndroid(e.Value))
+	}
+
+	b.WriteString("</resources>\n")
+	return []byte(b.String()), nil
+}
+
+var appleStringsEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+// encodeAppleStrings renders an export document as an Apple .strings file.
+// Plural keys are left to the .stringsdict export.
+func encodeAppleStrings(doc *exportDocument) ([]byte, error) {
+	var b strings.Builder
+	for _, e := range doc.Entries {
+		if e.IsPlural || e.Value == "" {
+			continue
+		}
+		if e.Description != "" {
+			fmt.Fprintf(&b, "/* %s */\n", strings.ReplaceAll(e.Description, "*/", "* /"))
+		}
+		fmt.Fprintf(&b, "\"%s\" = \"%s\";\n\n", appleStringsEscaper.Replace(e.Key), appleStringsEscaper.Replace(e.Value))
+	}
+	return []byte(b.String()), nil
+}
+
+// encodeAppleStringsdict renders the plural keys of an export document as an
+// Apple .stringsdict property list
+func encodeAppleStringsdict(doc *exportDocument) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	b.WriteString("<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n")
+	b.WriteString("<plist version=\"1.0\">\n<dict>\n")
+
+	for _, e := range doc.Entries {
+		if !e.IsPlural {
+			continue
+		}
+		forms := ""
+		for _, category := range pluralCategories {
+			if value := e.Forms[category]; value != "" {
+				forms += fmt.Sprintf("\t\t\t<key>%s</key>\n\t\t\t<string>%s</string>\n", category, escapeXMLText(value))
+			}
+		}
+		if forms == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\t<key>%s</key>\n\t<dict>\n", escapeXMLText(e.Key))
+		b.WriteString("\t\t<key>NSStringLocalizedFormatKey</key>\n\t\t<string>%#@count@</string>\n")
+		b.WriteString("\t\t<key>count</key>\n\t\t<dict>\n")
+		b.WriteString("\t\t\t<key>NSStringFormatSpecTypeKey</key>\n\t\t\t<string>NSStringPluralRuleType</string>\n")
+		b.WriteString("\t\t\t<key>NSStringFormatValueTypeKey</key>\n\t\t\t<string>d</string>\n")
+		b.WriteString(forms)
+		b.WriteString("\t\t</dict>\n\t</dict>\n")
+	}
+
+	b.WriteString("</dict>\n</plist>\n")
+	return []byte(b.String()), nil
+}
+
+var xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func escapeXMLText(s string) string {
+	return xmlTextEscaper.Replace(s)
+}
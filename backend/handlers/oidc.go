@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"translate-management/cache"
+	"translate-management/models"
+	"translate-management/oidc"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// oidcStateTTL bounds how long a user can take at the identity provider
+	oidcStateTTL = 10 * time.Minute
+	// oidcLoginCodeTTL bounds how long the frontend has to redeem a login code
+	oidcLoginCodeTTL = time.Minute
+	// oidcStateCookie holds the hash of the state of the browser's login attempt
+	oidcStateCookie = "oidc_state"
+)
+
+// oidcAttempt is a pending single sign-on login, stored under its state
+type oidcAttempt struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// errSSO is an error whose message can be shown on the login page
+type errSSO string
+
+func (e errSSO) Error() string { return string(e) }
+
+// Methods lists the login methods the frontend should offer
+func (h *AuthHandler) Methods(c *fiber.Ctx) error {
+	methods := fiber.Map{"password": !h.Cfg.DisablePasswordLogin, "oidc": h.OIDC != nil}
+	if h.OIDC != nil {
+		methods["oidc_name"] = h.Cfg.OIDCProviderName
+	}
+	return c.JSON(methods)
+}
+
+// OIDCLogin starts a single sign-on login: it remembers a state, nonce and
+// PKCE verifier for the attempt and redirects to the identity provider
+func (h *AuthHandler) OIDCLogin(c *fiber.Ctx) error {
+	if h.OIDC == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Single sign-on is not configured"})
+	}
+
+	var state string
+	var attempt oidcAttempt
+	var err error
+	for _, v := range []*string{&state, &attempt.Nonce, &attempt.Verifier} {
+		if *v, err = oidc.RandomString(); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start login"})
+		}
+	}
+
+	authURL, err := h.OIDC.AuthCodeURL(context.Background(), state, attempt.Nonce, attempt.Verifier)
+	if err != nil {
+		log.Printf("Error starting single sign-on: %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Identity provider is unavailable"})
+	}
+
+	data, _ := json.Marshal(attempt)
+	if err := h.Cache.Set(context.Background(), cache.OIDCStateKey(state), data, oidcStateTTL); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start login"})
+	}
+
+	// Tie the attempt to this browser, so a callback URL handed to someone
+	// else can't sign them in to the account that started it
+	h.setStateCookie(c, hashToken(state), time.Now().Add(oidcStateTTL))
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCCallback finishes a single sign-on login at the identity provider's
+// redirect. The browser is sent back to the frontend with a one-time code
+// for POST /api/auth/oidc/token, or with ?sso_error=... on the login page.
+func (h *AuthHandler) OIDCCallback(c *fiber.Ctx) error {
+	if h.OIDC == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Single sign-on is not configured"})
+	}
+
+	user, err := h.completeOIDCLogin(c)
+	if err != nil {
+		var ssoErr errSSO
+		if !errors.As(err, &ssoErr) {
+			log.Printf("Error completing single sign-on: %v", err)
+			ssoErr = "Single sign-on failed"
+		}
+		return c.Redirect(h.appURL("/login")+"?sso_error="+url.QueryEscape(ssoErr.Error()), fiber.StatusFound)
+	}
+
+	code, err := generateOpaqueToken()
+	if err == nil {
+		err = h.Cache.Set(context.Background(), cache.OIDCLoginCodeKey(code), []byte(user.ID), oidcLoginCodeTTL)
+	}
+	if err != nil {
+		return c.Redirect(h.appURL("/login")+"?sso_error="+url.QueryEscape("Single sign-on failed"), fiber.StatusFound)
+	}
+
+	return c.Redirect(h.appURL("/oidc-callback")+"?code="+code, fiber.StatusFound)
+}
+
+func (h *AuthHandler) completeOIDCLogin(c *fiber.Ctx) (models.User, error) {
+	if idpErr := c.Query("error", ""); idpErr != "" {
+		return models.User{}, errSSO("Identity provider refused the login: " + idpErr)
+	}
+
+	state := c.Query("state", "")
+	stateCookie := c.Cookies(oidcStateCookie)
+	h.setStateCookie(c, "", time.Unix(0, 0))
+	if state == "" || subtle.ConstantTimeCompare([]byte(stateCookie), []byte(hashToken(state))) != 1 {
+		return models.User{}, errSSO("Login was started in another browser, please try again")
+	}
+
+	data, err := h.Cache.GetDel(context.Background(), cache.OIDCStateKey(state))
+	if err != nil {
+		return models.User{}, err
+	}
+	var attempt oidcAttempt
+	if data == nil || json.Unmarshal(data, &attempt) != nil {
+		return models.User{}, errSSO("Login attempt expired, please try again")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	rawIDToken, err := h.OIDC.Exchange(ctx, c.Query("code", ""), attempt.Verifier)
+	if err != nil {
+		return models.User{}, err
+	}
+	claims, err := h.OIDC.Verify(ctx, rawIDToken, attempt.Nonce)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return h.provisionOIDCUser(claims)
+}
+
+// provisionOIDCUser finds the user linked to the identity, links an existing
+// account with the same (provider-verified) email, or creates a new account
+func (h *AuthHandler) provisionOIDCUser(claims *oidc.Claims) (models.User, error) {
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return models.User{}, err
+	}
+	defer tx.Rollback(context.Background())
+
+	issuer := h.OIDC.Issuer()
+	var user models.User
+	err = tx.QueryRow(context.Background(),
+		`UPDATE user_identities ui SET last_login_at = NOW(), email = $3
+		 FROM users u
+		 WHERE u.id = ui.user_id AND ui.issuer = $1 AND ui.subject = $2
+		 RETURNING u.id, u.email, u.username, u.name, u.avatar_url, u.email_verified, u.created_at, u.updated_at`,
+		issuer, claims.Subject, claims.Email,
+	).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.AvatarURL, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	if err == nil {
+		return user, tx.Commit(context.Background())
+	}
+	if err != pgx.ErrNoRows {
+		return models.User{}, err
+	}
+
+	if claims.Email == "" {
+		return models.User{}, errSSO("Identity provider did not share an email address")
+	}
+
+	err = tx.QueryRow(context.Background(),
+		`SELECT id, email, username, name, avatar_url, email_verified, created_at, updated_at
+		 FROM users WHERE LOWER(email) = LOWER($1)`,
+		claims.Email,
+	).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.AvatarURL, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	switch {
+	case err == nil:
+		// Linking by an unverified address would let anyone claim the account
+		if !claims.EmailVerified {
+			return models.User{}, errSSO("An account with this email exists, but the identity provider has not verified the address")
+		}
+		// Nor by an address the account never proved: whoever registered it
+		// would keep their password to an account the provider's user now owns
+		if !user.EmailVerified {
+			return models.User{}, errSSO("An account with this email exists, but its address has not been verified. Verify it or reset the password first, then sign in with single sign-on again.")
+		}
+	case err == pgx.ErrNoRows:
+		if user, err = createOIDCUser(tx, claims); err != nil {
+			return models.User{}, err
+		}
+	default:
+		return models.User{}, err
+	}
+
+	if _, err := tx.Exec(context.Background(),
+		`INSERT INTO user_identities (user_id, issuer, subject, email) VALUES ($1, $2, $3, $4)`,
+		user.ID, issuer, claims.Subject, claims.Email,
+	); err != nil {
+		return models.User{}, err
+	}
+
+	return user, tx.Commit(context.Background())
+}
+
+// createOIDCUser creates an account without a password. The username comes
+// from the preferred_username claim or the email's local part, with a
+// numeric suffix when it is taken.
+func createOIDCUser(tx pgx.Tx, claims *oidc.Claims) (models.User, error) {
+	base := strings.TrimSpace(claims.PreferredUsername)
+	if base == "" || strings.Contains(base, "@") {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if len(base) > 90 {
+		base = base[:90]
+	}
+	name := claims.Name
+	if name == "" {
+		name = base
+	}
+
+	username := base
+	for i := 2; ; i++ {
+		var taken bool
+		if err := tx.QueryRow(context.Background(),
+			`SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)`, username,
+		).Scan(&taken); err != nil {
+			return models.User{}, err
+		}
+		if !taken {
+			break
+		}
+		if i > 100 {
+			return models.User{}, errSSO("Could not choose a username for the new account")
+		}
+		username = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	var user models.User
+	err := tx.QueryRow(context.Background(),
+		`INSERT INTO users (email, username, password_hash, name, email_verified)
+		 VALUES ($1, $2, '', $3, $4)
+		 RETURNING id, email, username, name, avatar_url, email_verified, created_at, updated_at`,
+		claims.Email, username, name, bool(claims.EmailVerified),
+	).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.AvatarURL, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	return user, err
+}
+
+// OIDCToken exchanges the one-time code from the single sign-on redirect for
+// the same access and refresh tokens as a password login, including its
+// second step for users with two-factor authentication
+func (h *AuthHandler) OIDCToken(c *fiber.Ctx) error {
+	var req models.OIDCTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Code is required"})
+	}
+
+	userID, err := h.Cache.GetDel(context.Background(), cache.OIDCLoginCodeKey(req.Code))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to complete login"})
+	}
+	if userID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired code"})
+	}
+
+	var user models.User
+	err = h.DB.QueryRow(context.Background(),
+		`SELECT id, email, username, name, avatar_url, email_verified, created_at, updated_at
+		 FROM users WHERE id = $1`,
+		string(userID),
+	).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.AvatarURL, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired code"})
+	}
+
+	return h.completeLogin(c, user)
+}
+
+// setStateCookie stores the hash of a login's state in the browser that
+// started it, or clears it when expires is in the past
+func (h *AuthHandler) setStateCookie(c *fiber.Ctx, value string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/api/auth/oidc",
+		Expires:  expires,
+		Secure:   strings.HasPrefix(h.Cfg.OIDCRedirectURL, "https://"),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// appURL returns a frontend URL for path
+func (h *AuthHandler) appURL(path string) string {
+	return strings.TrimRight(h.Cfg.AppURL, "/") + path
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"translate-management/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	mustachePlaceholder = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+	icuPlaceholder      = regexp.MustCompile(`\{\s*([\p{L}\p{N}_]+)\s*[,}]`)
+	// No space flag: the conversion must follow the %, so percentages in
+	// prose such as "50% off" or "100% sure" are not placeholders
+	printfPlaceholder = regexp.MustCompile(`%(?:\d+\$)?[-+0#]*\d*(?:\.\d+)?[sdifuxXoeEgGcp@]`)
+	htmlTag           = regexp.MustCompile(`</?([a-zA-Z][a-zA-Z0-9]*)\b[^<>]*?(/?)>`)
+)
+
+// placeholderToken is a placeholder found in a message, e.g. "{count}",
+// "{{name}}", "%1$d" or "<b>"
+type placeholderToken struct {
+	Kind  string // icu, mustache, printf or html
+	Token string
+}
+
+// extractPlaceholders returns the distinct placeholders of a message in order
+// of appearance. ICU arguments are reported by name, so "{count, plural, ...}"
+// yields "{count}", and the text of plural and select branches is not a
+// placeholder.
+func extractPlaceholders(message string) []placeholderToken {
+	tokens := []placeholderToken{}
+	seen := make(map[string]bool)
+	add := func(kind, token string) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, placeholderToken{Kind: kind, Token: token})
+		}
+	}
+
+	for _, m := range mustachePlaceholder.FindAllStringSubmatch(message, -1) {
+		add("mustache", "{{"+m[1]+"}}")
+	}
+	rest := mustachePlaceholder.ReplaceAllString(message, "")
+	if args, ok := icuArguments(rest); ok {
+		for _, name := range args {
+			add("icu", "{"+name+"}")
+		}
+	} else {
+		// Not valid ICU: fall back to anything shaped like an argument
+		for _, m := range icuPlaceholder.FindAllStringSubmatch(rest, -1) {
+			add("icu", "{"+m[1]+"}")
+		}
+	}
+	for _, m := range printfPlaceholder.FindAllString(strings.ReplaceAll(message, "%%", ""), -1) {
+		add("printf", m)
+	}
+	for _, m := range htmlTag.FindAllStringSubmatch(message, -1) {
+		tag := strings.ToLower(m[1])
+		switch {
+		case strings.HasPrefix(m[0], "</"):
+			add("html", "</"+tag+">")
+		case m[2] == "/":
+			add("html", "<"+tag+"/>")
+		default:
+			add("html", "<"+tag+">")
+		}
+	}
+	return tokens
+}
+
+// comparePlaceholders reports placeholders of source missing from target,
+// extra placeholders in target, and placeholders that look renamed
+func comparePlaceholders(source, target string) (missing, extra []string, renamed []models.PlaceholderRename) {
+	sourceTokens := extractPlaceholders(source)
+	targetTokens := extractPlaceholders(target)
+
+	inTarget := make(map[string]bool)
+	for _, t := range targetTokens {
+		inTarget[t.Token] = true
+	}
+	inSource := make(map[string]bool)
+	for _, t := range sourceTokens {
+		inSource[t.Token] = true
+	}
+
+	var missingTokens, extraTokens []placeholderToken
+	for _, t := range sourceTokens {
+		if !inTarget[t.Token] {
+			missingTokens = append(missingTokens, t)
+		}
+	}
+	for _, t := range targetTokens {
+		if !inSource[t.Token] {
+			extraTokens = append(extraTokens, t)
+		}
+	}
+
+	// When a kind has as many missing as extra tokens they are most likely
+	// renames; otherwise the tokens are reported as missing and extra
+	missingByKind := make(map[string]int)
+	extraByKind := make(map[string]int)
+	for _, t := range missingTokens {
+		missingByKind[t.Kind]++
+	}
+	for _, t := range extraTokens {
+		extraByKind[t.Kind]++
+	}
+	isRename := func(kind string) bool {
+		return kind != "html" && missingByKind[kind] == extraByKind[kind]
+	}
+
+	pending := make(map[string][]string)
+	for _, t := range extraTokens {
+		if isRename(t.Kind) {
+			pending[t.Kind] = append(pending[t.Kind], t.Token)
+		} else {
+			extra = append(extra, t.Token)
+		}
+	}
+	for _, t := range missingTokens {
+		if !isRename(t.Kind) {
+			missing = append(missing, t.Token)
+			continue
+		}
+		renamed = append(renamed, models.PlaceholderRename{From: t.Token, To: pending[t.Kind][0]})
+		pending[t.Kind] = pending[t.Kind][1:]
+	}
+	return missing, extra, renamed
+}
+
+// forEachCellValue calls check with each value of a translation and its
+// default-language counterpart: once for plain values, or per category for
+// plural forms, where a missing source form falls back to "other" and then to
+// the plain source. Untranslated values and values without a source are skipped.
+func forEachCellValue(source, value string, sourceForms, forms map[string]string, check func(category, source, value string)) {
+	if forms == nil {
+		if source != "" && value != "" {
+			check("", source, value)
+		}
+		return
+	}
+	for _, category := range pluralCategories {
+		sourceForm := sourceForms[category]
+		if sourceForm == "" {
+			sourceForm = sourceForms["other"]
+		}
+		if sourceForm == "" {
+			sourceForm = source
+		}
+		if sourceForm != "" && forms[category] != "" {
+			check(category, sourceForm, forms[category])
+		}
+	}
+}
+
+// checkPlaceholderCell compares a translation with its default-language value
+// and appends an issue per mismatching value (one per category for plurals)
+func checkPlaceholderCell(issues []models.PlaceholderIssue, cell models.PlaceholderIssue, source, value string, sourceForms, forms map[string]string) []models.PlaceholderIssue {
+	forEachCellValue(source, value, sourceForms, forms, func(category, source, value string) {
+		missing, extra, renamed := comparePlaceholders(source, value)
+		if len(missing) == 0 && len(extra) == 0 && len(renamed) == 0 {
+			return
+		}
+		issue := cell
+		issue.Category = category
+		issue.Missing, issue.Extra, issue.Renamed = missing, extra, renamed
+		issues = append(issues, issue)
+	})
+	return issues
+}
+
+// qaCell is a stored translation with its default-language value
+type qaCell struct {
+	KeyID        string
+	Key          string
+	LanguageID   string
+	LanguageCode string
+	Value        string
+	Forms        map[string]string // nil unless the key is plural
+	Source       string
+	SourceForms  map[string]string
+}
+
+// loadQACells reads the translations of a project that the quality checks
+// compare against the default language. langCode and keyIDs optionally narrow
+// the cells. The default language code is returned along with the cells, or
+// "" when the project has no default language.
+func loadQACells(db *pgxpool.Pool, projectID, langCode string, keyIDs []string) (string, []qaCell, error) {
+	cells := []qaCell{}
+
+	var defaultID, defaultCode string
+	err := db.QueryRow(context.Background(),
+		`SELECT id, code FROM languages WHERE project_id = $1 AND is_default = TRUE LIMIT 1`, projectID,
+	).Scan(&defaultID, &defaultCode)
+	if err != nil {
+		// Without a default language there is nothing to compare against
+		return "", cells, nil
+	}
+
+	query := `SELECT tk.id, tk.key, tk.is_plural, l.id, l.code,
+			t.value, t.plural_forms, COALESCE(s.value, ''), s.plural_forms
+		 FROM translations t
+		 JOIN translation_keys tk ON tk.id = t.key_id
+		 JOIN languages l ON l.id = t.language_id
+		 LEFT JOIN translations s ON s.key_id = t.key_id AND s.language_id = $2
+		 WHERE tk.project_id = $1 AND t.language_id <> $2`
+	args := []interface{}{projectID, defaultID}
+	if langCode != "" {
+		args = append(args, langCode)
+		query += fmt.Sprintf(` AND l.code = $%d`, len(args))
+	}
+	if keyIDs != nil {
+		args = append(args, keyIDs)
+		query += fmt.Sprintf(` AND tk.id = ANY($%d)`, len(args))
+	}
+	query += ` ORDER BY tk.key ASC, l.code ASC`
+
+	rows, err := db.Query(context.Background(), query, args...)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cell qaCell
+		var isPlural bool
+		var forms, sourceForms []byte
+		if err := rows.Scan(&cell.KeyID, &cell.Key, &isPlural, &cell.LanguageID, &cell.LanguageCode,
+			&cell.Value, &forms, &cell.Source, &sourceForms); err != nil {
+			continue
+		}
+		if isPlural && forms != nil {
+			cell.Forms = decodePluralForms(forms)
+			cell.SourceForms = decodePluralForms(sourceForms)
+		}
+		cells = append(cells, cell)
+	}
+	return defaultCode, cells, rows.Err()
+}
+
+// findPlaceholderIssues checks the placeholders of stored translations
+// against the default language
+func findPlaceholderIssues(cells []qaCell) []models.PlaceholderIssue {
+	issues := []models.PlaceholderIssue{}
+	for _, c := range cells {
+		issue := models.PlaceholderIssue{KeyID: c.KeyID, Key: c.Key, LanguageID: c.LanguageID, LanguageCode: c.LanguageCode}
+		issues = checkPlaceholderCell(issues, issue, c.Source, c.Value, c.SourceForms, c.Forms)
+	}
+	return issues
+}
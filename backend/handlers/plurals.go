@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// pluralCategories lists every CLDR plural category in canonical order
+var pluralCategories = []string{"zero", "one", "two", "few", "many", "other"}
+
+// pluralRule describes how a language forms plurals
+type pluralRule struct {
+	// Categories are the CLDR categories a translator is expected to fill
+	Categories []string
+	// GettextCategories maps gettext msgstr[n] indexes to CLDR categories
+	GettextCategories []string
+	// GettextForms is the Plural-Forms header expression
+	GettextForms string
+}
+
+var (
+	pluralRuleOther = pluralRule{
+		Categories:        []string{"other"},
+		GettextCategories: []string{"other"},
+		GettextForms:      "nplurals=1; plural=0;",
+	}
+	pluralRuleOneOther = pluralRule{
+		Categories:        []string{"one", "other"},
+		GettextCategories: []string{"one", "other"},
+		GettextForms:      "nplurals=2; plural=(n != 1);",
+	}
+	pluralRuleFrench = pluralRule{
+		Categories:        []string{"one", "other"},
+		GettextCategories: []string{"one", "other"},
+		GettextForms:      "nplurals=2; plural=(n > 1);",
+	}
+	pluralRuleEastSlavic = pluralRule{
+		Categories:        []string{"one", "few", "many", "other"},
+		GettextCategories: []string{"one", "few", "many"},
+		GettextForms:      "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);",
+	}
+	pluralRulePolish = pluralRule{
+		Categories:        []string{"one", "few", "many", "other"},
+		GettextCategories: []string{"one", "few", "many"},
+		GettextForms:      "nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);",
+	}
+	pluralRuleCzech = pluralRule{
+		Categories:        []string{"one", "few", "many", "other"},
+		GettextCategories: []string{"one", "few", "other"},
+		GettextForms:      "nplurals=3; plural=(n==1) ? 0 : (n>=2 && n<=4) ? 1 : 2;",
+	}
+	pluralRuleSouthSlavic = pluralRule{
+		Categories:        []string{"one", "few", "other"},
+		GettextCategories: []string{"one", "few", "other"},
+		GettextForms:      "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);",
+	}
+	pluralRuleRomanian = pluralRule{
+		Categories:        []string{"one", "few", "other"},
+		GettextCategories: []string{"one", "few", "other"},
+		GettextForms:      "nplurals=3; plural=(n==1 ? 0 : (n==0 || (n%100 > 0 && n%100 < 20)) ? 1 : 2);",
+	}
+	pluralRuleSlovenian = pluralRule{
+		Categories:        []string{"one", "two", "few", "other"},
+		GettextCategories: []string{"one", "two", "few", "other"},
+		GettextForms:      "nplurals=4; plural=(n%100==1 ? 0 : n%100==2 ? 1 : n%100==3 || n%100==4 ? 2 : 3);",
+	}
+	pluralRuleHebrew = pluralRule{
+		Categories:        []string{"one", "two", "other"},
+		GettextCategories: []string{"one", "two", "other"},
+		GettextForms:      "nplurals=3; plural=(n==1 ? 0 : n==2 ? 1 : 2);",
+	}
+	pluralRuleArabic = pluralRule{
+		Categories:        []string{"zero", "one", "two", "few", "many", "other"},
+		GettextCategories: []string{"zero", "one", "two", "few", "many", "other"},
+		GettextForms:      "nplurals=6; plural=(n==0 ? 0 : n==1 ? 1 : n==2 ? 2 : n%100>=3 && n%100<=10 ? 3 : n%100>=11 ? 4 : 5);",
+	}
+)
+
+// pluralRules maps base language codes to their plural rule.
+// Languages not listed fall back to one/other.
+var pluralRules = map[string]pluralRule{
+	"ja": pluralRuleOther, "zh": pluralRuleOther, "ko": pluralRuleOther,
+	"th": pluralRuleOther, "vi": pluralRuleOther, "id": pluralRuleOther,
+	"ms": pluralRuleOther, "lo": pluralRuleOther, "km": pluralRuleOther,
+	"my": pluralRuleOther,
+	"fr": pluralRuleFrench, "pt-br": pluralRuleFrench,
+	"ru": pluralRuleEastSlavic, "uk": pluralRuleEastSlavic, "be": pluralRuleEastSlavic,
+	"pl": pluralRulePolish,
+	"cs": pluralRuleCzech, "sk": pluralRuleCzech,
+	"hr": pluralRuleSouthSlavic, "sr": pluralRuleSouthSlavic, "bs": pluralRuleSouthSlavic,
+	"ro": pluralRuleRomanian,
+	"sl": pluralRuleSlovenian,
+	"he": pluralRuleHebrew,
+	"ar": pluralRuleArabic,
+}
+
+// pluralRuleFor returns the plural rule for a language code such as "pt-BR" or "zh_Hant"
+func pluralRuleFor(langCode string) pluralRule {
+	code := strings.ToLower(strings.ReplaceAll(langCode, "_", "-"))
+	if rule, ok := pluralRules[code]; ok {
+		return rule
+	}
+	if i := strings.Index(code, "-"); i > 0 {
+		if rule, ok := pluralRules[code[:i]]; ok {
+			return rule
+		}
+	}
+	return pluralRuleOneOther
+}
+
+// isPluralCategory reports whether s is a CLDR plural category name
+func isPluralCategory(s string) bool {
+	return containsString(pluralCategories, s)
+}
+
+// splitPluralKey splits a suffixed key such as "cart.items.one" into its base
+// key and plural category. ok is false when the key has no category suffix.
+// Import formats without native plural support use this layout.
+func splitPluralKey(key string) (base, category string, ok bool) {
+	i := strings.LastIndex(key, ".")
+	if i <= 0 || !isPluralCategory(key[i+1:]) {
+		return key, "", false
+	}
+	return key[:i], key[i+1:], true
+}
+
+// validatePluralForms rejects unknown plural category names
+func validatePluralForms(forms map[string]string) error {
+	for category := range forms {
+		if !isPluralCategory(category) {
+			return fmt.Errorf("Invalid plural category '%s'", category)
+		}
+	}
+	return nil
+}
+
+// pluralFormsComplete reports whether every category required by the
+// language has a non-empty value
+func pluralFormsComplete(forms map[string]string, langCode string) bool {
+	for _, category := range pluralRuleFor(langCode).Categories {
+		if forms[category] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// decodePluralForms decodes a translations.plural_forms JSONB value
+func decodePluralForms(data []byte) map[string]string {
+	forms := map[string]string{}
+	if len(data) > 0 {
+		_ = json.Unmarshal(data, &forms)
+	}
+	return forms
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -2,12 +2,9 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/vmihailenco/msgpack/v5"
 )
 
 type ProjectExportHandler struct {
@@ -31,72 +28,44 @@ func (h *ProjectExportHandler) ExportLanguage(c *fiber.Ctx) error {
 	langCode := c.Params("langCode")
 	format := c.Query("format", "json")
 	envID := c.Query("env_id", "")
+	approvedOnly := c.QueryBool("approved_only", false)
+	fallback := c.QueryBool("fallback", false)
 
-	if format != "json" && format != "msgpack" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format must be 'json' or 'msgpack'"})
+	if !isExportFormat(format) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": exportFormatError()})
 	}
 
-	// Get language ID
-	var languageID string
+	// Get language ID and project slug
+	var languageID, slug string
 	err := h.DB.QueryRow(context.Background(),
-		`SELECT id FROM languages WHERE project_id = $1 AND code = $2`, projectID, langCode,
-	).Scan(&languageID)
+		`SELECT l.id, p.slug FROM languages l JOIN projects p ON p.id = l.project_id
+		 WHERE l.project_id = $1 AND l.code = $2`, projectID, langCode,
+	).Scan(&languageID, &slug)
 
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Language not found"})
 	}
 
-	// Build query — optionally filter by environment
-	query := `SELECT tk.key, t.value
-		 FROM translation_keys tk
-		 LEFT JOIN translations t ON t.key_id = tk.id AND t.language_id = $2
-		 WHERE tk.project_id = $1`
-	args := []interface{}{projectID, languageID}
-	if envID != "" {
-		query += ` AND tk.id IN (SELECT key_id FROM key_environments WHERE env_id = $` + fmt.Sprint(len(args)+1) + `)`
-		args = append(args, envID)
-	}
-	query += ` ORDER BY tk.key`
-
-	rows, err := h.DB.Query(context.Background(), query, args...)
+	// Optionally filter by environment and apply its overrides
+	doc, err := loadExportDocument(h.DB, projectID, slug, languageID, langCode, envID, approvedOnly)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch translations"})
 	}
-	defer rows.Close()
 
-	flatMap := make(map[string]string)
-	for rows.Next() {
-		var key string
-		var value *string
-		if err := rows.Scan(&key, &value); err != nil {
-			continue
-		}
-		if value != nil {
-			flatMap[key] = *value
-		} else {
-			flatMap[key] = ""
+	if fallback {
+		if err := applyFallbacks(h.DB, doc, languageID, liveFallbacks(h.DB, projectID, envID, approvedOnly)); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to apply fallback languages"})
 		}
+		setFallbackHeaders(c, doc.Filled)
 	}
 
-	// Build nested structure from dot-notation keys
-	nested := buildNestedMap(flatMap)
-
-	var data []byte
-	if format == "msgpack" {
-		data, err = msgpack.Marshal(nested)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to encode MessagePack"})
-		}
-		c.Set("Content-Type", "application/x-msgpack")
-		c.Set("Content-Disposition", "attachment; filename="+langCode+".msgpack")
-	} else {
-		data, err = json.MarshalIndent(nested, "", "  ")
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to encode JSON"})
-		}
-		c.Set("Content-Type", "application/json")
-		c.Set("Content-Disposition", "attachment; filename="+langCode+".json")
+	data, err := encodeExport(format, doc)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to encode export"})
 	}
 
+	c.Set("Content-Type", exportContentType(format))
+	c.Set("Content-Disposition", "attachment; filename="+langCode+"."+exportFileExtension(format))
+
 	return c.Send(data)
 }
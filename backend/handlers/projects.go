@@ -27,7 +27,7 @@ func (h *ProjectHandler) List(c *fiber.Ctx) error {
 
 	var rows interface{ Close() }
 	query := `
-		SELECT DISTINCT p.id, p.name, p.slug, p.description, p.created_by, p.created_at, p.updated_at,
+		SELECT DISTINCT p.id, p.name, p.slug, p.description, p.icu_validation, p.require_2fa, p.created_by, p.created_at, p.updated_at,
 		CASE WHEN p.created_by = $1 THEN 'owner' ELSE COALESCE(pm.role, 'viewer') END as role
 		FROM projects p
 		LEFT JOIN project_members pm ON p.id = pm.project_id AND pm.user_id = $1
@@ -51,7 +51,7 @@ func (h *ProjectHandler) List(c *fiber.Ctx) error {
 	projects := []models.ProjectWithRole{}
 	for r.Next() {
 		var p models.ProjectWithRole
-		if err := r.Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt, &p.Role); err != nil {
+		if err := r.Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.ICUValidation, &p.Require2FA, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt, &p.Role); err != nil {
 			log.Printf("Error scanning project: %v", err)
 			continue
 		}
@@ -68,13 +68,13 @@ func (h *ProjectHandler) Get(c *fiber.Ctx) error {
 
 	var p models.ProjectWithRole
 	err := h.DB.QueryRow(context.Background(),
-		`SELECT p.id, p.name, p.slug, p.description, p.created_by, p.created_at, p.updated_at,
+		`SELECT p.id, p.name, p.slug, p.description, p.icu_validation, p.require_2fa, p.created_by, p.created_at, p.updated_at,
 		 CASE WHEN p.created_by = $2 THEN 'owner' ELSE COALESCE(pm.role, 'viewer') END as role
 		 FROM projects p
 		 LEFT JOIN project_members pm ON p.id = pm.project_id AND pm.user_id = $2
 		 WHERE p.id = $1 AND (p.created_by = $2 OR pm.user_id = $2)`,
 		id, userID,
-	).Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt, &p.Role)
+	).Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.ICUValidation, &p.Require2FA, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt, &p.Role)
 
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
@@ -102,9 +102,9 @@ func (h *ProjectHandler) Create(c *fiber.Ctx) error {
 	err := h.DB.QueryRow(context.Background(),
 		`INSERT INTO projects (name, slug, description, created_by) 
 		 VALUES ($1, $2, $3, $4) 
-		 RETURNING id, name, slug, description, created_by, created_at, updated_at`,
+		 RETURNING id, name, slug, description, icu_validation, require_2fa, created_by, created_at, updated_at`,
 		req.Name, slug, req.Description, userID,
-	).Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
+	).Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.ICUValidation, &p.Require2FA, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create project"})
@@ -127,13 +127,19 @@ func (h *ProjectHandler) Update(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name is required"})
 	}
 
+	// Owners must use two-factor authentication themselves to require it,
+	// or they would lock themselves out
+	if req.Require2FA != nil && *req.Require2FA && !c.Locals("mfa").(bool) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Sign in with two-factor authentication before requiring it"})
+	}
+
 	var p models.Project
 	err := h.DB.QueryRow(context.Background(),
-		`UPDATE projects SET name = $1, description = $2, updated_at = NOW() 
+		`UPDATE projects SET name = $1, description = $2, icu_validation = COALESCE($5, icu_validation), require_2fa = COALESCE($6, require_2fa), updated_at = NOW() 
 		 WHERE id = $3 AND created_by = $4
-		 RETURNING id, name, slug, description, created_by, created_at, updated_at`,
-		req.Name, req.Description, id, userID,
-	).Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
+		 RETURNING id, name, slug, description, icu_validation, require_2fa, created_by, created_at, updated_at`,
+		req.Name, req.Description, id, userID, req.ICUValidation, req.Require2FA,
+	).Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.ICUValidation, &p.Require2FA, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
 
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
@@ -188,10 +194,14 @@ func (h *ProjectHandler) Stats(c *fiber.Ctx) error {
 	// Language progress
 	progress := make(map[string]float64)
 	if totalKeys > 0 {
+		completed := make(map[string]int)
+
+		// Plain keys are complete once they have a non-empty value
 		rows, err := h.DB.Query(context.Background(),
-			`SELECT l.code, COUNT(t.id) 
+			`SELECT l.code, COUNT(tk.id) 
 			 FROM languages l 
 			 LEFT JOIN translations t ON t.language_id = l.id AND t.value != ''
+			 LEFT JOIN translation_keys tk ON tk.id = t.key_id AND tk.is_plural = FALSE
 			 WHERE l.project_id = $1 
 			 GROUP BY l.code`, id)
 		if err == nil {
@@ -200,10 +210,32 @@ func (h *ProjectHandler) Stats(c *fiber.Ctx) error {
 				var code string
 				var count int
 				if rows.Scan(&code, &count) == nil {
-					progress[code] = float64(count) / float64(totalKeys) * 100
+					completed[code] = count
 				}
 			}
 		}
+
+		// Plural keys are complete once every category of the language is filled
+		pluralRows, err := h.DB.Query(context.Background(),
+			`SELECT l.code, t.plural_forms
+			 FROM translations t
+			 JOIN languages l ON l.id = t.language_id
+			 JOIN translation_keys tk ON tk.id = t.key_id AND tk.is_plural = TRUE
+			 WHERE l.project_id = $1 AND t.plural_forms IS NOT NULL`, id)
+		if err == nil {
+			defer pluralRows.Close()
+			for pluralRows.Next() {
+				var code string
+				var forms []byte
+				if pluralRows.Scan(&code, &forms) == nil && pluralFormsComplete(decodePluralForms(forms), code) {
+					completed[code]++
+				}
+			}
+		}
+
+		for code, count := range completed {
+			progress[code] = float64(count) / float64(totalKeys) * 100
+		}
 	}
 
 	return c.JSON(models.ProjectStats{
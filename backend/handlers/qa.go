@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+
+	"translate-management/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type QAHandler struct {
+	DB *pgxpool.Pool
+}
+
+func NewQAHandler(db *pgxpool.Pool) *QAHandler {
+	return &QAHandler{DB: db}
+}
+
+// Report runs the quality checks over a project's translations. ?lang=xx
+// limits the report to one language.
+func (h *QAHandler) Report(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	// Verify membership (any role)
+	var exists bool
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT EXISTS(
+			SELECT 1 FROM projects p
+			LEFT JOIN project_members pm ON p.id = pm.project_id
+			WHERE p.id = $1 AND (p.created_by = $2 OR pm.user_id = $2)
+		)`, projectID, userID).Scan(&exists)
+	if err != nil || !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or access denied"})
+	}
+
+	defaultLang, cells, err := loadQACells(h.DB, projectID, c.Query("lang", ""), nil)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check translations"})
+	}
+	if defaultLang == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Project has no default language"})
+	}
+
+	terms, err := loadGlossary(h.DB, projectID, "")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch glossary"})
+	}
+
+	return c.JSON(models.QAReport{
+		DefaultLanguage: defaultLang,
+		Placeholders:    findPlaceholderIssues(cells),
+		Glossary:        findGlossaryIssues(cells, terms),
+	})
+}
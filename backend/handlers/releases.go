@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"context"
+
+	"translate-management/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReleaseHandler struct {
+	DB *pgxpool.Pool
+}
+
+func NewReleaseHandler(db *pgxpool.Pool) *ReleaseHandler {
+	return &ReleaseHandler{DB: db}
+}
+
+// List returns all releases of a project, newest first
+func (h *ReleaseHandler) List(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	// Verify membership (any role)
+	var exists bool
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT EXISTS(
+			SELECT 1 FROM projects p
+			LEFT JOIN project_members pm ON p.id = pm.project_id
+			WHERE p.id = $1 AND (p.created_by = $2 OR pm.user_id = $2)
+		)`, projectID, userID).Scan(&exists)
+	if err != nil || !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or access denied"})
+	}
+
+	rows, err := h.DB.Query(context.Background(),
+		`SELECT r.id, r.project_id, r.env_id, r.name, r.description, r.source_language,
+			(SELECT COUNT(DISTINCT key) FROM release_entries WHERE release_id = r.id),
+			r.created_by, r.created_at
+		 FROM releases r WHERE r.project_id = $1 ORDER BY r.created_at DESC`,
+		projectID,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch releases"})
+	}
+	defer rows.Close()
+
+	releases := []models.Release{}
+	for rows.Next() {
+		var r models.Release
+		if err := rows.Scan(&r.ID, &r.ProjectID, &r.EnvID, &r.Name, &r.Description, &r.SourceLanguage,
+			&r.KeyCount, &r.CreatedBy, &r.CreatedAt); err != nil {
+			continue
+		}
+		releases = append(releases, r)
+	}
+
+	return c.JSON(releases)
+}
+
+// Create freezes the current keys and values of every language, optionally
+// of a single environment, into a named release
+func (h *ReleaseHandler) Create(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	// Verify project membership and role
+	var role string
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT
+			CASE
+				WHEN p.created_by = $2 THEN 'owner'
+				ELSE pm.role
+			END as role
+		FROM projects p
+		LEFT JOIN project_members pm ON p.id = pm.project_id AND pm.user_id = $2
+		WHERE p.id = $1`,
+		projectID, userID).Scan(&role)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or access denied"})
+	}
+	if role != "owner" && role != "editor" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	var req models.CreateReleaseRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Release name is required"})
+	}
+
+	var envID *string
+	if req.EnvID != "" {
+		var envExists bool
+		if err := h.DB.QueryRow(context.Background(),
+			`SELECT EXISTS(SELECT 1 FROM environments WHERE id = $1 AND project_id = $2)`, req.EnvID, projectID,
+		).Scan(&envExists); err != nil || !envExists {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Environment not found"})
+		}
+		envID = &req.EnvID
+	}
+
+	var taken bool
+	_ = h.DB.QueryRow(context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM releases WHERE project_id = $1 AND name = $2)`, projectID, req.Name,
+	).Scan(&taken)
+	if taken {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A release with this name already exists"})
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	var r models.Release
+	err = tx.QueryRow(context.Background(),
+		`INSERT INTO releases (project_id, env_id, name, description, source_language, created_by)
+		 VALUES ($1, $2, $3, $4,
+		 	COALESCE((SELECT code FROM languages WHERE project_id = $1 AND is_default = TRUE LIMIT 1), ''), $5)
+		 RETURNING id, project_id, env_id, name, description, source_language, created_by, created_at`,
+		projectID, envID, req.Name, req.Description, userID,
+	).Scan(&r.ID, &r.ProjectID, &r.EnvID, &r.Name, &r.Description, &r.SourceLanguage, &r.CreatedBy, &r.CreatedAt)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create release"})
+	}
+
+	// Snapshot every key in every language, with environment overrides applied
+	value, forms := exportCellSQL("t", "o", "$4")
+	_, err = tx.Exec(context.Background(),
+		`INSERT INTO release_entries (release_id, language_code, key, description, is_plural, value, plural_forms)
+		 SELECT $1, l.code, tk.key, COALESCE(tk.description, ''), tk.is_plural,
+		 	`+value+`, `+forms+`
+		 FROM translation_keys tk
+		 JOIN languages l ON l.project_id = tk.project_id
+		 LEFT JOIN translations t ON t.key_id = tk.id AND t.language_id = l.id
+		 LEFT JOIN translation_overrides o ON o.key_id = tk.id AND o.language_id = l.id AND o.env_id = $3::uuid
+		 WHERE tk.project_id = $2
+		 	AND ($3::uuid IS NULL OR tk.id IN (SELECT key_id FROM key_environments WHERE env_id = $3::uuid))`,
+		r.ID, projectID, envID, req.ApprovedOnly,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to snapshot translations"})
+	}
+
+	// Freeze the fallback chains too, so ?fallback=true serves the release
+	// as it was taken
+	_, err = tx.Exec(context.Background(),
+		`INSERT INTO release_languages (release_id, language_code, fallbacks)
+		 SELECT $1, code, fallbacks FROM languages WHERE project_id = $2`,
+		r.ID, projectID,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to snapshot translations"})
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit release"})
+	}
+
+	_ = h.DB.QueryRow(context.Background(),
+		`SELECT COUNT(DISTINCT key) FROM release_entries WHERE release_id = $1`, r.ID,
+	).Scan(&r.KeyCount)
+
+	return c.Status(fiber.StatusCreated).JSON(r)
+}
+
+// Diff compares two releases of a project given by name (?from=...&to=...)
+func (h *ReleaseHandler) Diff(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	// Verify membership (any role)
+	var exists bool
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT EXISTS(
+			SELECT 1 FROM projects p
+			LEFT JOIN project_members pm ON p.id = pm.project_id
+			WHERE p.id = $1 AND (p.created_by = $2 OR pm.user_id = $2)
+		)`, projectID, userID).Scan(&exists)
+	if err != nil || !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or access denied"})
+	}
+
+	fromName, toName := c.Query("from", ""), c.Query("to", "")
+	if fromName == "" || toName == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Both from and to releases are required"})
+	}
+
+	var fromID, toID string
+	if err := h.DB.QueryRow(context.Background(),
+		`SELECT id FROM releases WHERE project_id = $1 AND name = $2`, projectID, fromName,
+	).Scan(&fromID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Release '" + fromName + "' not found"})
+	}
+	if err := h.DB.QueryRow(context.Background(),
+		`SELECT id FROM releases WHERE project_id = $1 AND name = $2`, projectID, toName,
+	).Scan(&toID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Release '" + toName + "' not found"})
+	}
+
+	rows, err := h.DB.Query(context.Background(),
+		`SELECT COALESCE(a.key, b.key), COALESCE(a.language_code, b.language_code),
+			a.value, b.value, a.plural_forms, b.plural_forms
+		 FROM (SELECT * FROM release_entries WHERE release_id = $1) a
+		 FULL OUTER JOIN (SELECT * FROM release_entries WHERE release_id = $2) b
+		 	ON a.key = b.key AND a.language_code = b.language_code
+		 WHERE a.key IS NULL OR b.key IS NULL
+		 	OR a.value <> b.value OR a.plural_forms IS DISTINCT FROM b.plural_forms
+		 ORDER BY 1, 2`,
+		fromID, toID,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to compare releases"})
+	}
+	defer rows.Close()
+
+	diff := models.ReleaseDiff{
+		From:    fromName,
+		To:      toName,
+		Changes: []models.ReleaseChange{},
+		Summary: map[string]int{"added": 0, "removed": 0, "changed": 0},
+	}
+	for rows.Next() {
+		var ch models.ReleaseChange
+		var fromForms, toForms []byte
+		if err := rows.Scan(&ch.Key, &ch.LanguageCode, &ch.From, &ch.To, &fromForms, &toForms); err != nil {
+			continue
+		}
+		switch {
+		case ch.From == nil:
+			ch.Change = "added"
+		case ch.To == nil:
+			ch.Change = "removed"
+		default:
+			ch.Change = "changed"
+		}
+		if fromForms != nil {
+			ch.FromPluralForms = decodePluralForms(fromForms)
+		}
+		if toForms != nil {
+			ch.ToPluralForms = decodePluralForms(toForms)
+		}
+		diff.Summary[ch.Change]++
+		diff.Changes = append(diff.Changes, ch)
+	}
+
+	return c.JSON(diff)
+}
+
+// loadReleaseDocument builds an export document from a release snapshot
+func loadReleaseDocument(db *pgxpool.Pool, releaseID, slug, sourceLang, langCode string) (*exportDocument, error) {
+	doc := &exportDocument{ProjectSlug: slug, SourceLang: sourceLang, TargetLang: langCode}
+	if doc.SourceLang == "" {
+		doc.SourceLang = langCode
+	}
+
+	rows, err := db.Query(context.Background(),
+		`SELECT t.key, t.description, t.is_plural, t.value, t.plural_forms, COALESCE(s.value, ''), s.plural_forms
+		 FROM release_entries t
+		 LEFT JOIN release_entries s ON s.release_id = t.release_id AND s.key = t.key AND s.language_code = $3
+		 WHERE t.release_id = $1 AND t.language_code = $2
+		 ORDER BY t.key`,
+		releaseID, langCode, doc.SourceLang,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e exportEntry
+		var forms, sourceForms []byte
+		if err := rows.Scan(&e.Key, &e.Description, &e.IsPlural, &e.Value, &forms, &e.Source, &sourceForms); err != nil {
+			continue
+		}
+		if e.IsPlural {
+			e.Forms = decodePluralForms(forms)
+			e.SourceForms = decodePluralForms(sourceForms)
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+
+	return doc, rows.Err()
+}
+
+// releaseFallbacks reads fallback values from a release snapshot
+func releaseFallbacks(db *pgxpool.Pool, releaseID string) fallbackSource {
+	return func(code string) (pgx.Rows, error) {
+		return db.Query(context.Background(),
+			`SELECT key, value, plural_forms FROM release_entries WHERE release_id = $1 AND language_code = $2`,
+			releaseID, code,
+		)
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"translate-management/cache"
+	"translate-management/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// reviewTransition is a move in the review workflow
+// (untranslated -> draft -> in_review -> approved / rejected)
+type reviewTransition struct {
+	From  []string // statuses the action applies to
+	To    string
+	Roles []string // project roles allowed to perform it
+}
+
+var reviewTransitions = map[string]reviewTransition{
+	"submit":  {From: []string{"draft", "rejected"}, To: "in_review", Roles: []string{"owner", "editor"}},
+	"approve": {From: []string{"in_review"}, To: "approved", Roles: []string{"owner", "reviewer"}},
+	"reject":  {From: []string{"in_review"}, To: "rejected", Roles: []string{"owner", "reviewer"}},
+}
+
+type ReviewHandler struct {
+	DB    *pgxpool.Pool
+	Cache *cache.RedisClient
+}
+
+func NewReviewHandler(db *pgxpool.Pool, rdb *cache.RedisClient) *ReviewHandler {
+	return &ReviewHandler{DB: db, Cache: rdb}
+}
+
+// resetReviewStatus moves a cell whose value changed back to draft, or to
+// untranslated when it was emptied, and records whether the new value came
+// from machine translation. The last approved value is kept.
+func resetReviewStatus(tx pgx.Tx, keyID, languageID, envID string, cur *translationCell, machine bool) error {
+	status := "draft"
+	if cur.Value == "" && len(decodePluralForms(cur.Forms)) == 0 {
+		status = "untranslated"
+	}
+	query := `UPDATE translations SET status = $3, machine_translated = $4 WHERE key_id = $1 AND language_id = $2`
+	args := []interface{}{keyID, languageID, status, machine}
+	if envID != "" {
+		query = `UPDATE translation_overrides SET status = $3, machine_translated = $4 WHERE key_id = $1 AND language_id = $2 AND env_id = $5`
+		args = append(args, envID)
+	}
+	_, err := tx.Exec(context.Background(), query, args...)
+	return err
+}
+
+// Review applies a workflow action (submit, approve or reject) to translation
+// cells. Editors submit drafts for review; reviewers approve or reject them.
+// With ?env_id=... the action applies to the environment's overrides. Cells
+// that are missing or not in a state the action applies to are skipped.
+func (h *ReviewHandler) Review(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	// Verify project membership and role
+	var role string
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT
+			CASE
+				WHEN p.created_by = $2 THEN 'owner'
+				ELSE pm.role
+			END as role
+		FROM projects p
+		LEFT JOIN project_members pm ON p.id = pm.project_id AND pm.user_id = $2
+		WHERE p.id = $1`,
+		projectID, userID).Scan(&role)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found or access denied"})
+	}
+
+	var req models.ReviewTranslationsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	transition, ok := reviewTransitions[req.Action]
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Action must be one of: submit, approve, reject"})
+	}
+	if !containsString(transition.Roles, role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+	if len(req.Translations) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No translations provided"})
+	}
+
+	envID := c.Query("env_id", "")
+	if envID != "" {
+		var envExists bool
+		if err := h.DB.QueryRow(context.Background(),
+			`SELECT EXISTS(SELECT 1 FROM environments WHERE id = $1 AND project_id = $2)`, envID, projectID,
+		).Scan(&envExists); err != nil || !envExists {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Environment not found"})
+		}
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	table, where := "translations t", ""
+	if envID != "" {
+		table, where = "translation_overrides t", " AND t.env_id = $4"
+	}
+
+	updated := 0
+	skipped := []models.SkippedReview{}
+	for _, cell := range req.Translations {
+		args := []interface{}{cell.KeyID, cell.LanguageID, projectID}
+		if envID != "" {
+			args = append(args, envID)
+		}
+
+		var status string
+		err := tx.QueryRow(context.Background(),
+			`SELECT t.status FROM `+table+`
+			 JOIN translation_keys tk ON tk.id = t.key_id
+			 WHERE t.key_id = $1 AND t.language_id = $2 AND tk.project_id = $3`+where+`
+			 FOR UPDATE OF t`,
+			args...,
+		).Scan(&status)
+		if err == pgx.ErrNoRows {
+			skipped = append(skipped, models.SkippedReview{KeyID: cell.KeyID, LanguageID: cell.LanguageID, Reason: "Translation not found"})
+			continue
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read translation"})
+		}
+		if !containsString(transition.From, status) {
+			skipped = append(skipped, models.SkippedReview{
+				KeyID:      cell.KeyID,
+				LanguageID: cell.LanguageID,
+				Status:     status,
+				Reason:     fmt.Sprintf("Cannot %s a translation that is %s", req.Action, status),
+			})
+			continue
+		}
+
+		query := `UPDATE translations SET status = $3`
+		cond := ` WHERE key_id = $1 AND language_id = $2`
+		args = []interface{}{cell.KeyID, cell.LanguageID, transition.To}
+		if envID != "" {
+			query = `UPDATE translation_overrides SET status = $3`
+			args = append(args, envID)
+			cond += ` AND env_id = $4`
+		}
+		if transition.To != "in_review" {
+			args = append(args, userID)
+			query += fmt.Sprintf(`, reviewed_by = $%d, reviewed_at = NOW()`, len(args))
+		}
+		if transition.To == "approved" {
+			query += `, approved_value = value, approved_plural_forms = plural_forms`
+		}
+		query += cond
+		if _, err := tx.Exec(context.Background(), query, args...); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update translation status"})
+		}
+		if transition.To == "approved" && envID == "" {
+			if err := indexTranslationMemory(tx, cell.KeyID, cell.LanguageID); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update translation memory"})
+			}
+		}
+		updated++
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
+
+	// Approvals change what approved_only exports serve
+	if transition.To == "approved" && updated > 0 {
+		var slug string
+		_ = h.DB.QueryRow(context.Background(), "SELECT slug FROM projects WHERE id = $1", projectID).Scan(&slug)
+		if slug != "" {
+			_ = h.Cache.DeleteByPattern(context.Background(), cache.ProjectCachePattern(slug))
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Review status updated",
+		"status":  transition.To,
+		"updated": updated,
+		"skipped": skipped,
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"translate-management/models"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -41,7 +42,7 @@ func (h *TranslationHandler) Get(c *fiber.Ctx) error {
 	envID := c.Query("env_id", "")
 
 	// Get all keys (optionally filtered by env)
-	keyQuery := `SELECT id, key, description FROM translation_keys WHERE project_id = $1`
+	keyQuery := `SELECT id, key, description, is_plural FROM translation_keys WHERE project_id = $1`
 	keyArgs := []interface{}{projectID}
 	argIdx := 2
 	if envID != "" {
@@ -66,14 +67,17 @@ func (h *TranslationHandler) Get(c *fiber.Ctx) error {
 
 	for keyRows.Next() {
 		var keyID, key, desc string
-		if err := keyRows.Scan(&keyID, &key, &desc); err != nil {
+		var isPlural bool
+		if err := keyRows.Scan(&keyID, &key, &desc, &isPlural); err != nil {
 			continue
 		}
 		entries = append(entries, models.TranslationEntry{
 			KeyID:       keyID,
 			Key:         key,
 			Description: desc,
+			IsPlural:    isPlural,
 			Values:      make(map[string]string),
+			Statuses:    make(map[string]string),
 		})
 		keyIDs = append(keyIDs, keyID)
 	}
@@ -84,7 +88,7 @@ func (h *TranslationHandler) Get(c *fiber.Ctx) error {
 
 	// Get all translations for these keys
 	tRows, err := h.DB.Query(context.Background(),
-		`SELECT t.key_id, t.language_id, t.value
+		`SELECT t.key_id, t.language_id, t.value, t.plural_forms, t.status, t.machine_translated
 		 FROM translations t
 		 JOIN translation_keys tk ON t.key_id = tk.id
 		 WHERE tk.project_id = $1`,
@@ -96,22 +100,90 @@ func (h *TranslationHandler) Get(c *fiber.Ctx) error {
 	defer tRows.Close()
 
 	// Build a map for quick lookup
-	translationMap := make(map[string]map[string]string) // key_id -> language_id -> value
+	translationMap := make(map[string]map[string]string)       // key_id -> language_id -> value
+	pluralMap := make(map[string]map[string]map[string]string) // key_id -> language_id -> category -> value
+	statusMap := make(map[string]map[string]string)            // key_id -> language_id -> review status
+	machineMap := make(map[string]map[string]bool)             // key_id -> language_id -> machine-translated
 	for tRows.Next() {
-		var keyID, langID, value string
-		if err := tRows.Scan(&keyID, &langID, &value); err != nil {
+		var keyID, langID, value, status string
+		var forms []byte
+		var machine bool
+		if err := tRows.Scan(&keyID, &langID, &value, &forms, &status, &machine); err != nil {
 			continue
 		}
+		if machine {
+			if machineMap[keyID] == nil {
+				machineMap[keyID] = make(map[string]bool)
+			}
+			machineMap[keyID][langID] = true
+		}
 		if translationMap[keyID] == nil {
 			translationMap[keyID] = make(map[string]string)
+			statusMap[keyID] = make(map[string]string)
 		}
 		translationMap[keyID][langID] = value
+		statusMap[keyID][langID] = status
+		if forms != nil {
+			if pluralMap[keyID] == nil {
+				pluralMap[keyID] = make(map[string]map[string]string)
+			}
+			pluralMap[keyID][langID] = decodePluralForms(forms)
+		}
+	}
+
+	// Values overridden in the selected environment
+	overrideMap := make(map[string]map[string]string)
+	pluralOverrideMap := make(map[string]map[string]map[string]string)
+	overrideStatusMap := make(map[string]map[string]string)
+	if envID != "" {
+		oRows, err := h.DB.Query(context.Background(),
+			`SELECT o.key_id, o.language_id, o.value, o.plural_forms, o.status
+			 FROM translation_overrides o
+			 JOIN translation_keys tk ON o.key_id = tk.id
+			 WHERE tk.project_id = $1 AND o.env_id = $2`,
+			projectID, envID,
+		)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch overrides"})
+		}
+		defer oRows.Close()
+
+		for oRows.Next() {
+			var keyID, langID, value, status string
+			var forms []byte
+			if err := oRows.Scan(&keyID, &langID, &value, &forms, &status); err != nil {
+				continue
+			}
+			if overrideMap[keyID] == nil {
+				overrideMap[keyID] = make(map[string]string)
+				overrideStatusMap[keyID] = make(map[string]string)
+			}
+			overrideMap[keyID][langID] = value
+			overrideStatusMap[keyID][langID] = status
+			if forms != nil {
+				if pluralOverrideMap[keyID] == nil {
+					pluralOverrideMap[keyID] = make(map[string]map[string]string)
+				}
+				pluralOverrideMap[keyID][langID] = decodePluralForms(forms)
+			}
+		}
 	}
 
 	// Merge translations into entries
 	for i := range entries {
+		entries[i].Overrides = overrideMap[entries[i].KeyID]
+		entries[i].PluralOverrides = pluralOverrideMap[entries[i].KeyID]
+		entries[i].OverrideStatuses = overrideStatusMap[entries[i].KeyID]
+		entries[i].MachineTranslated = machineMap[entries[i].KeyID]
 		if vals, ok := translationMap[entries[i].KeyID]; ok {
 			entries[i].Values = vals
+			entries[i].Statuses = statusMap[entries[i].KeyID]
+		}
+		if entries[i].IsPlural {
+			entries[i].Plurals = pluralMap[entries[i].KeyID]
+			if entries[i].Plurals == nil {
+				entries[i].Plurals = make(map[string]map[string]string)
+			}
 		}
 	}
 
@@ -153,29 +225,95 @@ func (h *TranslationHandler) BatchUpdate(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No translations provided"})
 	}
 
+	// Writes go to environment overrides when an environment is selected
+	envID := c.Query("env_id", "")
+	if envID != "" {
+		var envExists bool
+		if err := h.DB.QueryRow(context.Background(),
+			`SELECT EXISTS(SELECT 1 FROM environments WHERE id = $1 AND project_id = $2)`, envID, projectID,
+		).Scan(&envExists); err != nil || !envExists {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Environment not found"})
+		}
+	}
+
+	for _, t := range req.Translations {
+		if err := validatePluralForms(t.PluralForms); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	// Exports read plural keys from plural_forms only, so the shape of each
+	// cell has to match its key
+	keyIDs := make([]string, 0, len(req.Translations))
+	for _, t := range req.Translations {
+		keyIDs = append(keyIDs, t.KeyID)
+	}
+	pluralKeys := make(map[string]bool)
+	rows, err := h.DB.Query(context.Background(),
+		`SELECT id FROM translation_keys WHERE project_id = $1 AND id = ANY($2) AND is_plural = TRUE`, projectID, keyIDs,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch keys"})
+	}
+	for rows.Next() {
+		var keyID string
+		if err := rows.Scan(&keyID); err == nil {
+			pluralKeys[keyID] = true
+		}
+	}
+	rows.Close()
+	for _, t := range req.Translations {
+		if t.PluralForms != nil && !pluralKeys[t.KeyID] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "plural_forms can only be set on plural keys"})
+		}
+	}
+
+	// Reject malformed ICU messages before anything is written
+	if icuValidationEnabled(h.DB, projectID) {
+		invalid := []models.TranslationValidationError{}
+		for _, t := range req.Translations {
+			if envID != "" && t.ResetOverride {
+				continue
+			}
+			cell := models.TranslationValidationError{KeyID: t.KeyID, LanguageID: t.LanguageID}
+			invalid = validateICUForms(invalid, cell, t.Value, t.PluralForms)
+		}
+		if len(invalid) > 0 {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Invalid ICU message format", "errors": invalid})
+		}
+	}
+
 	tx, err := h.DB.Begin(context.Background())
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
 	}
 	defer tx.Rollback(context.Background())
 
-	for _, t := range req.Translations {
-		_, err := tx.Exec(context.Background(),
-			`INSERT INTO translations (key_id, language_id, value, updated_by) 
-			 VALUES ($1, $2, $3, $4) 
-			 ON CONFLICT (key_id, language_id) 
-			 DO UPDATE SET value = EXCLUDED.value, updated_at = NOW(), updated_by = EXCLUDED.updated_by`,
-			t.KeyID, t.LanguageID, t.Value, userID,
-		)
+	for i, t := range req.Translations {
+		old, err := readTranslationCell(tx, t.KeyID, t.LanguageID, envID)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update translation"})
 		}
+		if pluralKeys[t.KeyID] && t.PluralForms == nil && !(envID != "" && t.ResetOverride) {
+			if t.PluralForms, err = pluralValueForms(tx, envID, t, old); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update translation"})
+			}
+			req.Translations[i] = t
+		}
+		if err := writeTranslation(tx, envID, userID, t); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update translation"})
+		}
+		if err := recordHistory(tx, t.KeyID, t.LanguageID, envID, userID, "update", old, nil); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record history"})
+		}
 	}
 
 	if err := tx.Commit(context.Background()); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
 	}
 
+	warnings, glossary := h.updateWarnings(projectID, envID, req.Translations)
+
 	// Invalidate cache after successful update
 	var slug string
 	_ = h.DB.QueryRow(context.Background(), "SELECT slug FROM projects WHERE id = $1", projectID).Scan(&slug)
@@ -183,5 +321,149 @@ func (h *TranslationHandler) BatchUpdate(c *fiber.Ctx) error {
 		_ = h.Cache.DeleteByPattern(context.Background(), cache.ProjectCachePattern(slug))
 	}
 
-	return c.JSON(fiber.Map{"message": "Translations updated", "count": len(req.Translations)})
+	return c.JSON(fiber.Map{
+		"message":  "Translations updated",
+		"count":    len(req.Translations),
+		"warnings": warnings,
+		"glossary": glossary,
+	})
+}
+
+// updateWarnings reports placeholder mismatches and missing glossary terms
+// against the default language for the cells of a batch update. They do not
+// block the save.
+func (h *TranslationHandler) updateWarnings(projectID, envID string, updates []models.TranslationUpdate) ([]models.PlaceholderIssue, []models.GlossaryIssue) {
+	warnings := []models.PlaceholderIssue{}
+	glossary := []models.GlossaryIssue{}
+
+	var defaultID string
+	if err := h.DB.QueryRow(context.Background(),
+		`SELECT id FROM languages WHERE project_id = $1 AND is_default = TRUE LIMIT 1`, projectID,
+	).Scan(&defaultID); err != nil {
+		return warnings, glossary
+	}
+	terms, _ := loadGlossary(h.DB, projectID, "")
+
+	keyIDs := make([]string, 0, len(updates))
+	for _, t := range updates {
+		keyIDs = append(keyIDs, t.KeyID)
+	}
+	rows, err := h.DB.Query(context.Background(),
+		`SELECT tk.id, tk.key, COALESCE(t.value, ''), t.plural_forms
+		 FROM translation_keys tk
+		 LEFT JOIN translations t ON t.key_id = tk.id AND t.language_id = $2
+		 WHERE tk.project_id = $1 AND tk.id = ANY($3)`,
+		projectID, defaultID, keyIDs,
+	)
+	if err != nil {
+		return warnings, glossary
+	}
+	defer rows.Close()
+
+	type sourceValue struct {
+		key   string
+		value string
+		forms map[string]string
+	}
+	sources := make(map[string]sourceValue)
+	for rows.Next() {
+		var keyID string
+		var src sourceValue
+		var forms []byte
+		if err := rows.Scan(&keyID, &src.key, &src.value, &forms); err != nil {
+			continue
+		}
+		if forms != nil {
+			src.forms = decodePluralForms(forms)
+		}
+		sources[keyID] = src
+	}
+
+	for _, t := range updates {
+		src, ok := sources[t.KeyID]
+		if !ok || t.LanguageID == defaultID || (envID != "" && t.ResetOverride) {
+			continue
+		}
+		value, sourceForms := t.Value, map[string]string(nil)
+		if t.PluralForms != nil {
+			value, sourceForms = t.PluralForms["other"], src.forms
+		}
+		cell := models.PlaceholderIssue{KeyID: t.KeyID, Key: src.key, LanguageID: t.LanguageID}
+		warnings = checkPlaceholderCell(warnings, cell, src.value, value, sourceForms, t.PluralForms)
+		term := models.GlossaryIssue{KeyID: t.KeyID, Key: src.key, LanguageID: t.LanguageID}
+		glossary = checkGlossaryCell(glossary, term, terms, src.value, value, sourceForms, t.PluralForms)
+	}
+	return warnings, glossary
+}
+
+// writeTranslation writes one cell of a batch update, to the environment
+// override when envID is set
+func writeTranslation(tx pgx.Tx, envID, userID string, t models.TranslationUpdate) error {
+	if envID != "" {
+		return saveOverride(tx, envID, userID, t)
+	}
+
+	var err error
+	if t.PluralForms != nil {
+		// Plural keys store every category; value mirrors the "other" form
+		_, err = tx.Exec(context.Background(),
+			`INSERT INTO translations (key_id, language_id, value, plural_forms, updated_by) 
+			 VALUES ($1, $2, $3, $4, $5) 
+			 ON CONFLICT (key_id, language_id) 
+			 DO UPDATE SET value = EXCLUDED.value, plural_forms = EXCLUDED.plural_forms, updated_at = NOW(), updated_by = EXCLUDED.updated_by`,
+			t.KeyID, t.LanguageID, t.PluralForms["other"], t.PluralForms, userID,
+		)
+	} else {
+		_, err = tx.Exec(context.Background(),
+			`INSERT INTO translations (key_id, language_id, value, updated_by) 
+			 VALUES ($1, $2, $3, $4) 
+			 ON CONFLICT (key_id, language_id) 
+			 DO UPDATE SET value = EXCLUDED.value, updated_at = NOW(), updated_by = EXCLUDED.updated_by`,
+			t.KeyID, t.LanguageID, t.Value, userID,
+		)
+	}
+	return err
+}
+
+// pluralValueForms turns a plain value sent for a plural key into its
+// "other" form, keeping the cell's other categories. A new override starts
+// from the base translation's forms.
+func pluralValueForms(tx pgx.Tx, envID string, t models.TranslationUpdate, old *translationCell) (map[string]string, error) {
+	if old == nil && envID != "" {
+		var err error
+		if old, err = readTranslationCell(tx, t.KeyID, t.LanguageID, ""); err != nil {
+			return nil, err
+		}
+	}
+	forms := map[string]string{}
+	if old != nil {
+		forms = decodePluralForms(old.Forms)
+	}
+	forms["other"] = t.Value
+	return forms, nil
+}
+
+// saveOverride writes or, with ResetOverride, removes the environment
+// override of a translation cell
+func saveOverride(tx pgx.Tx, envID, userID string, t models.TranslationUpdate) error {
+	if t.ResetOverride {
+		_, err := tx.Exec(context.Background(),
+			`DELETE FROM translation_overrides WHERE key_id = $1 AND language_id = $2 AND env_id = $3`,
+			t.KeyID, t.LanguageID, envID,
+		)
+		return err
+	}
+
+	value := t.Value
+	if t.PluralForms != nil {
+		value = t.PluralForms["other"]
+	}
+	_, err := tx.Exec(context.Background(),
+		`INSERT INTO translation_overrides (key_id, language_id, env_id, value, plural_forms, updated_by)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (key_id, language_id, env_id)
+		 DO UPDATE SET value = EXCLUDED.value, plural_forms = EXCLUDED.plural_forms, updated_at = NOW(), updated_by = EXCLUDED.updated_by`,
+		t.KeyID, t.LanguageID, envID, value, t.PluralForms, userID,
+	)
+	return err
 }
@@ -0,0 +1,375 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"log"
+	"strings"
+	"time"
+
+	"translate-management/cache"
+	"translate-management/middleware"
+	"translate-management/models"
+	"translate-management/totp"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	totpIssuer        = "Translate Management"
+	recoveryCodeCount = 10
+	// maxMFAAttempts is the number of codes that can be tried per login
+	maxMFAAttempts = 5
+)
+
+// completeLogin finishes a first-factor login. Users with two-factor
+// authentication get an "mfa pending" token for POST /api/auth/2fa/verify
+// instead of a session.
+func (h *AuthHandler) completeLogin(c *fiber.Ctx, user models.User) error {
+	var enabled bool
+	if err := h.DB.QueryRow(context.Background(),
+		`SELECT totp_enabled FROM users WHERE id = $1`, user.ID,
+	).Scan(&enabled); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load user"})
+	}
+	if !enabled {
+		return h.startSession(c, user, fiber.StatusOK, false)
+	}
+
+	token, err := middleware.GenerateMFAToken(user.ID, h.Cfg)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+	}
+	return c.JSON(models.MFAChallenge{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int(middleware.MFATokenTTL.Seconds()),
+	})
+}
+
+// checkSecondFactor accepts a current TOTP code (not used before) or an
+// unused recovery code of the user, locking the user's row
+func checkSecondFactor(tx pgx.Tx, userID, code string) (bool, error) {
+	var secret *string
+	var lastStep int64
+	err := tx.QueryRow(context.Background(),
+		`SELECT totp_secret, totp_last_step FROM users WHERE id = $1 FOR UPDATE`, userID,
+	).Scan(&secret, &lastStep)
+	if err != nil || secret == nil {
+		return false, err
+	}
+
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if step, ok := totp.Validate(*secret, code, time.Now()); ok {
+		if step <= lastStep {
+			return false, nil
+		}
+		_, err := tx.Exec(context.Background(), `UPDATE users SET totp_last_step = $2 WHERE id = $1`, userID, step)
+		return err == nil, err
+	}
+
+	tag, err := tx.Exec(context.Background(),
+		`UPDATE user_recovery_codes SET used_at = NOW()
+		 WHERE id = (
+		 	SELECT id FROM user_recovery_codes
+		 	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+		 	LIMIT 1
+		 )`,
+		userID, hashToken(normalizeRecoveryCode(code)),
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// normalizeRecoveryCode ignores case and the dash recovery codes are shown with
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
+
+// replaceRecoveryCodes discards the user's recovery codes and returns a new
+// set, formatted as xxxxx-xxxxx
+func replaceRecoveryCodes(tx pgx.Tx, userID string) ([]string, error) {
+	if _, err := tx.Exec(context.Background(), `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(enc.EncodeToString(b))[:10]
+		if _, err := tx.Exec(context.Background(),
+			`INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hashToken(raw),
+		); err != nil {
+			return nil, err
+		}
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+	}
+	return codes, nil
+}
+
+// VerifyTwoFactor is the second login step: it exchanges the "mfa pending"
+// token and a TOTP or recovery code for a session
+func (h *AuthHandler) VerifyTwoFactor(c *fiber.Ctx) error {
+	var req models.VerifyTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	claims, err := middleware.ParseMFAToken(req.MFAToken, h.Cfg)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired login, please sign in again"})
+	}
+	used, err := h.Cache.Exists(context.Background(), cache.MFAUsedKey(claims.ID))
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Unable to verify code"})
+	}
+	if used {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired login, please sign in again"})
+	}
+	attempts, err := h.Cache.Incr(context.Background(), cache.MFAAttemptsKey(claims.ID), middleware.MFATokenTTL)
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Unable to verify code"})
+	}
+	if attempts > maxMFAAttempts {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many attempts, please sign in again"})
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	ok, err := checkSecondFactor(tx, claims.UserID, req.Code)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify code"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid code"})
+	}
+
+	var user models.User
+	err = tx.QueryRow(context.Background(),
+		`SELECT id, email, username, name, avatar_url, email_verified, created_at, updated_at
+		 FROM users WHERE id = $1`,
+		claims.UserID,
+	).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.AvatarURL, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
+
+	if err := h.Cache.Set(context.Background(), cache.MFAUsedKey(claims.ID), []byte("1"), middleware.MFATokenTTL); err != nil {
+		log.Printf("Error marking mfa token used: %v", err)
+	}
+
+	return h.startSession(c, user, fiber.StatusOK, true)
+}
+
+// TwoFactorStatus reports whether the current user has two-factor
+// authentication enabled and how many recovery codes are left
+func (h *AuthHandler) TwoFactorStatus(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var enabled bool
+	var remaining int
+	err := h.DB.QueryRow(context.Background(),
+		`SELECT u.totp_enabled,
+			(SELECT COUNT(*) FROM user_recovery_codes rc WHERE rc.user_id = u.id AND rc.used_at IS NULL)
+		 FROM users u WHERE u.id = $1`,
+		userID,
+	).Scan(&enabled, &remaining)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	return c.JSON(fiber.Map{
+		"enabled":                  enabled,
+		"session_mfa":              c.Locals("mfa").(bool),
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// EnrollTwoFactor creates a new TOTP secret for the current user and returns
+// it with its otpauth:// URI. Two-factor authentication is enabled once a
+// code from the app is confirmed.
+func (h *AuthHandler) EnrollTwoFactor(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	email := c.Locals("user_email").(string)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate secret"})
+	}
+
+	tag, err := h.DB.Exec(context.Background(),
+		`UPDATE users SET totp_secret = $2, totp_last_step = 0 WHERE id = $1 AND NOT totp_enabled`,
+		userID, secret,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start enrollment"})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+
+	return c.JSON(models.TwoFactorEnrollment{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(totpIssuer, email, secret),
+	})
+}
+
+// ConfirmTwoFactor enables two-factor authentication with a code from the
+// enrolled app and returns the recovery codes, shown only this once. The
+// current session keeps its first-factor status; signing in again with the
+// code gives access to projects that require two-factor authentication.
+func (h *AuthHandler) ConfirmTwoFactor(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	var secret *string
+	var enabled bool
+	err = tx.QueryRow(context.Background(),
+		`SELECT totp_secret, totp_enabled FROM users WHERE id = $1 FOR UPDATE`, userID,
+	).Scan(&secret, &enabled)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if enabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+	if secret == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Start enrollment first"})
+	}
+
+	step, ok := totp.Validate(*secret, strings.TrimSpace(req.Code), time.Now())
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid code"})
+	}
+	if _, err := tx.Exec(context.Background(),
+		`UPDATE users SET totp_enabled = TRUE, totp_last_step = $2, updated_at = NOW() WHERE id = $1`, userID, step,
+	); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to enable two-factor authentication"})
+	}
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate recovery codes"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after checking a TOTP
+// or recovery code
+func (h *AuthHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	ok, err := checkSecondFactor(tx, userID, req.Code)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify code"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid code"})
+	}
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate recovery codes"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
+
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// DisableTwoFactor turns two-factor authentication off after checking a TOTP
+// or recovery code, and ends every session of the user
+func (h *AuthHandler) DisableTwoFactor(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	tx, err := h.DB.Begin(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to begin transaction"})
+	}
+	defer tx.Rollback(context.Background())
+
+	var enabled bool
+	if err := tx.QueryRow(context.Background(),
+		`SELECT totp_enabled FROM users WHERE id = $1`, userID,
+	).Scan(&enabled); err != nil || !enabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+	ok, err := checkSecondFactor(tx, userID, req.Code)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify code"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid code"})
+	}
+
+	if _, err := tx.Exec(context.Background(),
+		`UPDATE users SET totp_enabled = FALSE, totp_secret = NULL, totp_last_step = 0, updated_at = NOW() WHERE id = $1`, userID,
+	); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to disable two-factor authentication"})
+	}
+	if _, err := tx.Exec(context.Background(), `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to disable two-factor authentication"})
+	}
+	// Sessions started with the second factor must not keep that status
+	if _, err := tx.Exec(context.Background(),
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID,
+	); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke sessions"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+	}
+
+	if err := h.Cache.RevokeUserTokens(context.Background(), userID, h.Cfg.AccessTokenTTL); err != nil {
+		log.Printf("Error revoking access tokens: %v", err)
+	}
+
+	return c.JSON(fiber.Map{"message": "Two-factor authentication disabled; sign in again"})
+}
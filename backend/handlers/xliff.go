@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// XLIFF 1.2 document structure
+type xliff12Document struct {
+	XMLName xml.Name      `xml:"xliff"`
+	Xmlns   string        `xml:"xmlns,attr,omitempty"`
+	Version string        `xml:"version,attr"`
+	Files   []xliff12File `xml:"file"`
+}
+
+type xliff12File struct {
+	Original       string        `xml:"original,attr"`
+	SourceLanguage string        `xml:"source-language,attr"`
+	TargetLanguage string        `xml:"target-language,attr,omitempty"`
+	Datatype       string        `xml:"datatype,attr"`
+	Units          []xliff12Unit `xml:"body>trans-unit"`
+}
+
+type xliff12Unit struct {
+	ID      string  `xml:"id,attr"`
+	ResName string  `xml:"resname,attr,omitempty"`
+	Source  string  `xml:"source"`
+	Target  *string `xml:"target"`
+	Note    string  `xml:"note,omitempty"`
+}
+
+// XLIFF 2.0 document structure
+type xliff20Document struct {
+	XMLName xml.Name      `xml:"xliff"`
+	Xmlns   string        `xml:"xmlns,attr,omitempty"`
+	Version string        `xml:"version,attr"`
+	SrcLang string        `xml:"srcLang,attr"`
+	TrgLang string        `xml:"trgLang,attr,omitempty"`
+	Files   []xliff20File `xml:"file"`
+}
+
+type xliff20File struct {
+	ID    string        `xml:"id,attr"`
+	Units []xliff20Unit `xml:"unit"`
+}
+
+type xliff20Unit struct {
+	ID       string           `xml:"id,attr"`
+	Name     string           `xml:"name,attr,omitempty"`
+	Notes    *xliff20Notes    `xml:"notes"`
+	Segments []xliff20Segment `xml:"segment"`
+}
+
+type xliff20Notes struct {
+	Notes []string `xml:"note"`
+}
+
+type xliff20Segment struct {
+	Source string  `xml:"source"`
+	Target *string `xml:"target"`
+}
+
+// encodeXLIFF12 renders an export document as XLIFF 1.2
+func encodeXLIFF12(doc *exportDocument) ([]byte, error) {
+	file := xliff12File{
+		Original:       doc.ProjectSlug,
+		SourceLanguage: doc.SourceLang,
+		TargetLanguage: doc.TargetLang,
+		Datatype:       "plaintext",
+		Units:          make([]xliff12Unit, 0, len(doc.Entries)),
+	}
+	for _, u := range xliffUnits(doc) {
+		file.Units = append(file.Units, xliff12Unit{
+			ID:      u.Key,
+			ResName: u.Key,
+			Source:  u.Source,
+			Target:  xliffTarget(u.Value),
+			Note:    u.Description,
+		})
+	}
+
+	return marshalXLIFF(xliff12Document{
+		Xmlns:   "urn:oasis:names:tc:xliff:document:1.2",
+		Version: "1.2",
+		Files:   []xliff12File{file},
+	})
+}
+
+// encodeXLIFF20 renders an export document as XLIFF 2.0
+func encodeXLIFF20(doc *exportDocument) ([]byte, error) {
+	file := xliff20File{
+		ID:    doc.ProjectSlug,
+		Units: make([]xliff20Unit, 0, len(doc.Entries)),
+	}
+	for _, u := range xliffUnits(doc) {
+		unit := xliff20Unit{
+			ID:       u.Key,
+			Name:     u.Key,
+			Segments: []xliff20Segment{{Source: u.Source, Target: xliffTarget(u.Value)}},
+		}
+		if u.Description != "" {
+			unit.Notes = &xliff20Notes{Notes: []string{u.Description}}
+		}
+		file.Units = append(file.Units, unit)
+	}
+
+	return marshalXLIFF(xliff20Document{
+		Xmlns:   "urn:oasis:names:tc:xliff:document:2.0",
+		Version: "2.0",
+		SrcLang: doc.SourceLang,
+		TrgLang: doc.TargetLang,
+		Files:   []xliff20File{file},
+	})
+}
+
+// xliffUnits flattens plural keys into one unit per category, identified as
+// "<key>.<category>" so that imports can map them back to plural forms
+func xliffUnits(doc *exportDocument) []exportEntry {
+	units := make([]exportEntry, 0, len(doc.Entries))
+	for _, e := range doc.Entries {
+		if !e.IsPlural {
+			units = append(units, e)
+			continue
+		}
+		for _, category := range doc.targetCategories(e) {
+			units = append(units, exportEntry{
+				Key:         e.Key + "." + category,
+				Description: e.Description,
+				Source:      e.sourceForm(category),
+				Value:       e.Forms[category],
+			})
+		}
+	}
+	return units
+}
+
+// xliffTarget omits the <target> element for untranslated units
+func xliffTarget(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func marshalXLIFF(v interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// parseXLIFF reads an XLIFF 1.2 or 2.0 file and returns its target language
+// and translation units. Units without a target are skipped.
+func parseXLIFF(data []byte) (string, []importEntry, error) {
+	var probe struct {
+		Version string `xml:"version,attr"`
+	}
+	if err := xml.Unmarshal(data, &probe); err != nil {
+		return "", nil, fmt.Errorf("invalid XLIFF: %w", err)
+	}
+
+	entries := []importEntry{}
+	switch probe.Version {
+	case "1.2", "1.1", "1.0":
+		var doc xliff12Document
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return "", nil, fmt.Errorf("invalid XLIFF 1.2: %w", err)
+		}
+		targetLang := ""
+		for _, f := range doc.Files {
+			if targetLang == "" {
+				targetLang = f.TargetLanguage
+			}
+			for _, u := range f.Units {
+				if u.Target == nil {
+					continue
+				}
+				key := u.ResName
+				if key == "" {
+					key = u.ID
+				}
+				entries = append(entries, importEntry{Key: key, Value: *u.Target, Description: u.Note})
+			}
+		}
+		return targetLang, entries, nil
+	case "2.0", "2.1":
+		var doc xliff20Document
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return "", nil, fmt.Errorf("invalid XLIFF 2.0: %w", err)
+		}
+		for _, f := range doc.Files {
+			for _, u := range f.Units {
+				key := u.Name
+				if key == "" {
+					key = u.ID
+				}
+				// Multi-segment units are joined back into a single value
+				value, hasTarget := "", false
+				for _, s := range u.Segments {
+					if s.Target != nil {
+						value += *s.Target
+						hasTarget = true
+					}
+				}
+				if !hasTarget {
+					continue
+				}
+				description := ""
+				if u.Notes != nil && len(u.Notes.Notes) > 0 {
+					description = u.Notes.Notes[0]
+				}
+				entries = append(entries, importEntry{Key: key, Value: value, Description: description})
+			}
+		}
+		return doc.TrgLang, entries, nil
+	}
+
+	return "", nil, fmt.Errorf("unsupported XLIFF version %q", probe.Version)
+}
@@ -48,6 +48,7 @@ func main() {
 		AllowOrigins:     "http://localhost:5173",
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
 		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-API-Key",
+		ExposeHeaders:    "Content-Disposition,X-Cache,X-Fallback-Count,X-Fallback-Keys,X-Fallback-Keys-Truncated",
 		AllowCredentials: true,
 	}))
 
@@ -63,10 +64,12 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
+	shutdown := make(chan struct{})
 	go func() {
 		<-quit
 		log.Println("Shutting down server...")
 		_ = app.Shutdown()
+		close(shutdown)
 	}()
 
 	port := cfg.Port
@@ -78,4 +81,6 @@ func main() {
 	if err := app.Listen(":" + port); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
+	// Listen returns before the shutdown hooks have run
+	<-shutdown
 }
@@ -4,54 +4,114 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"net/netip"
+	"slices"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// APIKeyAuth middleware validates API keys from the X-API-Key header
-func APIKeyAuth(db *pgxpool.Pool) fiber.Handler {
+// APIKeyAuth middleware validates API keys from the X-API-Key header. Errors
+// carry a "code" so clients can tell why a key was refused: api_key_missing,
+// api_key_invalid, api_key_inactive, api_key_expired, api_key_rotated (an old
+// secret past its rotation overlap) or ip_not_allowed. Requests with a known
+// key, refused ones included, are counted by usage.
+func APIKeyAuth(db *pgxpool.Pool, usage *UsageRecorder) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		apiKey := c.Get("X-API-Key")
 		if apiKey == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "X-API-Key header required",
-			})
+			return apiKeyError(c, fiber.StatusUnauthorized, "api_key_missing", "X-API-Key header required")
 		}
 
-		// Hash the provided key and look it up
+		// Hash the provided key and look it up, by its current or previous secret
 		hash := sha256.Sum256([]byte(apiKey))
 		keyHash := fmt.Sprintf("%x", hash)
 
-		var projectID string
-		var isActive bool
+		var keyID, projectID string
+		var envID *string
+		var scopes, allowedCIDRs []string
+		var isActive, isCurrent bool
+		var expiresAt, previousExpiresAt *time.Time
 		err := db.QueryRow(context.Background(),
-			`SELECT project_id, is_active FROM api_keys WHERE key_hash = $1`,
+			`SELECT id, project_id, env_id, scopes, is_active, expires_at, allowed_cidrs,
+			 	key_hash = $1, previous_key_expires_at
+			 FROM api_keys WHERE key_hash = $1 OR previous_key_hash = $1
+			 LIMIT 1`,
 			keyHash,
-		).Scan(&projectID, &isActive)
+		).Scan(&keyID, &projectID, &envID, &scopes, &isActive, &expiresAt, &allowedCIDRs, &isCurrent, &previousExpiresAt)
 
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid API key",
-			})
+			return apiKeyError(c, fiber.StatusUnauthorized, "api_key_invalid", "Invalid API key")
+		}
+
+		refuse := func(status int, code, message string) error {
+			usage.record(c, keyID, !isCurrent, true)
+			return apiKeyError(c, status, code, message)
 		}
 
 		if !isActive {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "API key is inactive",
-			})
+			return refuse(fiber.StatusForbidden, "api_key_inactive", "API key is inactive")
+		}
+
+		now := time.Now()
+		if expiresAt != nil && !now.Before(*expiresAt) {
+			return refuse(fiber.StatusUnauthorized, "api_key_expired", "API key has expired")
 		}
 
-		// Update last used timestamp
-		go func() {
-			_, _ = db.Exec(context.Background(),
-				`UPDATE api_keys SET last_used_at = $1 WHERE key_hash = $2`,
-				time.Now(), keyHash,
-			)
-		}()
+		if !isCurrent && (previousExpiresAt == nil || !now.Before(*previousExpiresAt)) {
+			return refuse(fiber.StatusUnauthorized, "api_key_rotated", "API key has been rotated; use the new key")
+		}
+
+		if len(allowedCIDRs) > 0 && !ipAllowed(c.IP(), allowedCIDRs) {
+			return refuse(fiber.StatusForbidden, "ip_not_allowed", "API key is not allowed from this IP address")
+		}
 
 		c.Locals("project_id", projectID)
+		c.Locals("api_key_id", keyID)
+		c.Locals("api_key_scopes", scopes)
+		if envID != nil {
+			// Key is pinned to a single environment
+			c.Locals("api_key_env_id", *envID)
+		}
+
+		err = c.Next()
+		usage.record(c, keyID, !isCurrent, err != nil || c.Response().StatusCode() >= fiber.StatusBadRequest)
+		return err
+	}
+}
+
+// ipAllowed reports whether ip falls in one of the CIDR ranges
+func ipAllowed(ip string, cidrs []string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, cidr := range cidrs {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func apiKeyError(c *fiber.Ctx, status int, code, message string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"error": message,
+		"code":  code,
+	})
+}
+
+// RequireScope rejects API keys without the given scope. Must run after
+// APIKeyAuth.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		scopes, _ := c.Locals("api_key_scopes").([]string)
+		if !slices.Contains(scopes, scope) {
+			return apiKeyError(c, fiber.StatusForbidden, "insufficient_scope",
+				fmt.Sprintf("API key lacks the %q scope", scope))
+		}
 		return c.Next()
 	}
 }
@@ -1,9 +1,14 @@
 package middleware
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"strings"
 	"time"
 
+	"translate-management/cache"
 	"translate-management/config"
 
 	"github.com/gofiber/fiber/v2"
@@ -14,17 +19,51 @@ type Claims struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
+	// SessionID identifies the login (refresh token family) the token belongs to
+	SessionID string `json:"sid"`
+	// MFA is set when the session was started with a second factor
+	MFA bool `json:"mfa,omitempty"`
+	// Purpose marks tokens that are not access tokens, e.g. "mfa" for a
+	// login waiting for its second factor
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a JWT token for a user
-func GenerateToken(userID, username, email string, cfg *config.Config) (string, error) {
+// MFATokenTTL is how long a user has to enter their second factor
+const MFATokenTTL = 5 * time.Minute
+
+// GenerateToken creates a short-lived JWT access token for a user's session
+func GenerateToken(userID, username, email, sessionID string, mfa bool, cfg *config.Config) (string, error) {
+	claims := &Claims{
+		UserID:    userID,
+		Username:  username,
+		Email:     email,
+		SessionID: sessionID,
+		MFA:       mfa,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(cfg.AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(cfg.JWTSecret))
+}
+
+// GenerateMFAToken creates the "mfa pending" token returned by a password
+// login when the user has two-factor authentication enabled. It only grants
+// the second login step, not API access.
+func GenerateMFAToken(userID string, cfg *config.Config) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
+		UserID:  userID,
+		Purpose: "mfa",
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        hex.EncodeToString(id),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(MFATokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -33,8 +72,21 @@ func GenerateToken(userID, username, email string, cfg *config.Config) (string,
 	return token.SignedString([]byte(cfg.JWTSecret))
 }
 
-// AuthRequired middleware validates JWT tokens
-func AuthRequired(cfg *config.Config) fiber.Handler {
+// ParseMFAToken validates an "mfa pending" token and returns its claims
+func ParseMFAToken(tokenString string, cfg *config.Config) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(cfg.JWTSecret), nil
+	})
+	if err != nil || !token.Valid || claims.Purpose != "mfa" || claims.ID == "" {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+	return claims, nil
+}
+
+// AuthRequired middleware validates JWT tokens and rejects revoked ones.
+// Requests are refused when the revocation list can't be checked.
+func AuthRequired(cfg *config.Config, rdb *cache.RedisClient) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -55,15 +107,33 @@ func AuthRequired(cfg *config.Config) fiber.Handler {
 			return []byte(cfg.JWTSecret), nil
 		})
 
-		if err != nil || !token.Valid {
+		if err != nil || !token.Valid || claims.Purpose != "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid or expired token",
 			})
 		}
 
+		var issuedAt time.Time
+		if claims.IssuedAt != nil {
+			issuedAt = claims.IssuedAt.Time
+		}
+		revoked, err := rdb.IsTokenRevoked(context.Background(), claims.UserID, claims.SessionID, issuedAt)
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Unable to verify token",
+			})
+		}
+		if revoked {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Token has been revoked",
+			})
+		}
+
 		c.Locals("user_id", claims.UserID)
 		c.Locals("username", claims.Username)
 		c.Locals("user_email", claims.Email)
+		c.Locals("session_id", claims.SessionID)
+		c.Locals("mfa", claims.MFA)
 		return c.Next()
 	}
 }
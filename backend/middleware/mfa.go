@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ProjectMFARequired rejects requests to projects whose owner requires
+// two-factor authentication, unless the session was started with a second
+// factor. It runs on the /api/projects group before routes are matched, so
+// the project id is read from the path, ignoring case as the router does.
+// Must run after AuthRequired.
+func ProjectMFARequired(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		rest, found := strings.CutPrefix(strings.ToLower(c.Path()), "/api/projects/")
+		projectID, _, _ := strings.Cut(rest, "/")
+		if !found || projectID == "" || c.Locals("mfa").(bool) {
+			return c.Next()
+		}
+
+		// Unknown or malformed ids are left to the handlers
+		var required bool
+		err := db.QueryRow(context.Background(),
+			`SELECT require_2fa FROM projects WHERE id = $1`, projectID,
+		).Scan(&required)
+		if err != nil || !required {
+			return c.Next()
+		}
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "This project requires two-factor authentication. Enable it and sign in again.",
+			"code":  "mfa_required",
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"translate-management/cache"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimitConfig configures RateLimit
+type RateLimitConfig struct {
+	// Name keeps the counters of different limiters apart
+	Name string
+	// Max is the number of requests a client may make per Window
+	Max    int
+	Window time.Duration
+	// Key identifies the client. It defaults to the client IP; returning ""
+	// lets the request through uncounted.
+	Key func(c *fiber.Ctx) string
+}
+
+// RateLimit allows each client Max requests per fixed Window and answers
+// further requests with 429 and a Retry-After header. Redis errors let
+// requests through, so an outage doesn't lock everyone out.
+func RateLimit(rdb *cache.RedisClient, cfg RateLimitConfig) fiber.Handler {
+	if cfg.Key == nil {
+		cfg.Key = ClientIP
+	}
+
+	return func(c *fiber.Ctx) error {
+		client := cfg.Key(c)
+		if client == "" {
+			return c.Next()
+		}
+
+		count, ttl, err := rdb.Hit(context.Background(), cache.RateLimitKey(cfg.Name, client), cfg.Window)
+		if err != nil {
+			log.Printf("Error checking rate limit %s: %v", cfg.Name, err)
+			return c.Next()
+		}
+
+		c.Set("X-RateLimit-Limit", strconv.Itoa(cfg.Max))
+		c.Set("X-RateLimit-Remaining", strconv.FormatInt(max(int64(cfg.Max)-count, 0), 10))
+		if count > int64(cfg.Max) {
+			return tooManyRequests(c, ttl, "Too many requests, please try again later")
+		}
+		return c.Next()
+	}
+}
+
+// BackoffConfig configures Backoff
+type BackoffConfig struct {
+	// Name keeps the counters of different limiters apart
+	Name string
+	// Key identifies the client, as for RateLimitConfig
+	Key func(c *fiber.Ctx) string
+	// Threshold is the number of failures before the client is locked out
+	Threshold int
+	// The first lockout lasts BaseLock and each further failure doubles it,
+	// up to MaxLock. Failures are forgotten MaxLock after the last one.
+	BaseLock time.Duration
+	MaxLock  time.Duration
+	// ResetOnSuccess forgets the client's failures after a 2xx response.
+	// Leave it off for keys an attacker can succeed with on their own,
+	// such as the client IP.
+	ResetOnSuccess bool
+}
+
+// Backoff locks clients out after repeated failed attempts, doubling the
+// lockout with each further failure. A request fails when its
+// handler responds 401. Locked out clients get 429 with a Retry-After header
+// before the handler runs. Like RateLimit, it lets requests through when
+// Redis is unavailable.
+func Backoff(rdb *cache.RedisClient, cfg BackoffConfig) fiber.Handler {
+	if cfg.Key == nil {
+		cfg.Key = ClientIP
+	}
+
+	return func(c *fiber.Ctx) error {
+		client := cfg.Key(c)
+		if client == "" {
+			return c.Next()
+		}
+		failuresKey := cache.BackoffFailuresKey(cfg.Name, client)
+		lockKey := cache.BackoffLockKey(cfg.Name, client)
+
+		locked, err := rdb.TTL(context.Background(), lockKey)
+		if err != nil {
+			log.Printf("Error checking lockout %s: %v", cfg.Name, err)
+		}
+		if locked > 0 {
+			return tooManyRequests(c, locked, "Too many failed attempts, please try again later")
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		switch {
+		case status == fiber.StatusUnauthorized:
+			failures, err := rdb.Incr(context.Background(), failuresKey, cfg.MaxLock)
+			if err != nil {
+				log.Printf("Error recording failed attempt %s: %v", cfg.Name, err)
+				return nil
+			}
+			if failures >= int64(cfg.Threshold) {
+				lock := backoffDuration(cfg.BaseLock, cfg.MaxLock, failures-int64(cfg.Threshold))
+				if err := rdb.Set(context.Background(), lockKey, []byte("1"), lock); err != nil {
+					log.Printf("Error locking out client %s: %v", cfg.Name, err)
+				}
+			}
+		case cfg.ResetOnSuccess && status >= 200 && status < 300:
+			if err := rdb.Delete(context.Background(), failuresKey); err != nil {
+				log.Printf("Error resetting failed attempts %s: %v", cfg.Name, err)
+			}
+		}
+		return nil
+	}
+}
+
+// backoffDuration returns base doubled n times, capped at limit
+func backoffDuration(base, limit time.Duration, n int64) time.Duration {
+	if n >= 62 || float64(base)*math.Pow(2, float64(n)) >= float64(limit) {
+		return limit
+	}
+	return base << n
+}
+
+func tooManyRequests(c *fiber.Ctx, retryAfter time.Duration, message string) error {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(max(seconds, 1), 10))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": message})
+}
+
+// ClientIP keys limiters by the client's IP address
+func ClientIP(c *fiber.Ctx) string {
+	return c.IP()
+}
+
+// BodyField keys limiters by a field of the JSON or form request body, such
+// as the username of a login. Case is ignored so variants share a counter.
+// Requests without the field, or with a body that doesn't parse, share a
+// counter of their own rather than going uncounted.
+func BodyField(field string) func(c *fiber.Ctx) string {
+	return func(c *fiber.Ctx) string {
+		var value string
+		// Same content type rule as BodyParser, so the field is read from the
+		// body the handler will parse
+		ctype, _, _ := strings.Cut(strings.ToLower(c.Get(fiber.HeaderContentType)), ";")
+		if strings.HasSuffix(strings.TrimSpace(ctype), "json") {
+			var body map[string]any
+			if json.Unmarshal(c.Body(), &body) == nil {
+				value, _ = body[field].(string)
+			}
+		} else {
+			value = c.FormValue(field)
+		}
+
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			return "none"
+		}
+		return "value:" + value
+	}
+}
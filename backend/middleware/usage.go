@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// usageBucket identifies a row of api_key_usage
+type usageBucket struct {
+	KeyID    string
+	Hour     time.Time
+	Language string
+	Format   string
+}
+
+type usageCounts struct {
+	Requests, Errors, CacheHits, CacheMisses, OldSecret int
+}
+
+// UsageRecorder counts API key requests in memory and adds them to the hourly
+// api_key_usage buckets (and last_used_at) on every flush, so requests don't
+// wait for a database write
+type UsageRecorder struct {
+	db       *pgxpool.Pool
+	mu       sync.Mutex
+	counts   map[usageBucket]*usageCounts
+	lastUsed map[string]time.Time
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewUsageRecorder(db *pgxpool.Pool) *UsageRecorder {
+	return &UsageRecorder{
+		db:       db,
+		counts:   map[usageBucket]*usageCounts{},
+		lastUsed: map[string]time.Time{},
+	}
+}
+
+// Start flushes the recorded usage every interval until Stop
+func (u *UsageRecorder) Start(interval time.Duration) {
+	u.stop = make(chan struct{})
+	u.done = make(chan struct{})
+	go func() {
+		defer close(u.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				u.Flush(context.Background())
+			case <-u.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the flush loop and writes what is left
+func (u *UsageRecorder) Stop() {
+	if u.stop != nil {
+		close(u.stop)
+		<-u.done
+	}
+	u.Flush(context.Background())
+}
+
+// record counts a request made with an API key. Successful requests to
+// routes with a :langCode are counted under their language and format.
+func (u *UsageRecorder) record(c *fiber.Ctx, keyID string, oldSecret, failed bool) {
+	now := time.Now()
+	bucket := usageBucket{KeyID: keyID, Hour: now.Truncate(time.Hour)}
+	if !failed {
+		// Fiber reuses the memory of params and queries after the request.
+		// Lengths are capped by the columns; a row too long would fail the flush.
+		lang, format := c.Params("langCode"), c.Query("format", "json")
+		if lang != "" && len(lang) <= 10 && len(format) <= 20 {
+			bucket.Language = strings.Clone(lang)
+			bucket.Format = strings.Clone(format)
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	counts := u.counts[bucket]
+	if counts == nil {
+		counts = &usageCounts{}
+		u.counts[bucket] = counts
+	}
+	counts.Requests++
+	if failed {
+		counts.Errors++
+	}
+	if oldSecret {
+		counts.OldSecret++
+	}
+	switch c.GetRespHeader("X-Cache") {
+	case "HIT":
+		counts.CacheHits++
+	case "MISS":
+		counts.CacheMisses++
+	}
+	u.lastUsed[keyID] = now
+}
+
+// Flush writes the usage recorded since the last flush. Usage that fails to
+// write is kept for the next flush.
+func (u *UsageRecorder) Flush(ctx context.Context) {
+	u.mu.Lock()
+	counts, lastUsed := u.counts, u.lastUsed
+	u.counts, u.lastUsed = map[usageBucket]*usageCounts{}, map[string]time.Time{}
+	u.mu.Unlock()
+	if len(counts) == 0 && len(lastUsed) == 0 {
+		return
+	}
+
+	batch := &pgx.Batch{}
+	for b, n := range counts {
+		// Skips keys deleted with their project since the request
+		batch.Queue(
+			`INSERT INTO api_key_usage
+			 	(api_key_id, hour, language_code, format, requests, errors, cache_hits, cache_misses, old_secret_requests)
+			 SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9
+			 WHERE EXISTS (SELECT 1 FROM api_keys WHERE id = $1)
+			 ON CONFLICT (api_key_id, hour, language_code, format) DO UPDATE SET
+			 	requests = api_key_usage.requests + EXCLUDED.requests,
+			 	errors = api_key_usage.errors + EXCLUDED.errors,
+			 	cache_hits = api_key_usage.cache_hits + EXCLUDED.cache_hits,
+			 	cache_misses = api_key_usage.cache_misses + EXCLUDED.cache_misses,
+			 	old_secret_requests = api_key_usage.old_secret_requests + EXCLUDED.old_secret_requests`,
+			b.KeyID, b.Hour, b.Language, b.Format, n.Requests, n.Errors, n.CacheHits, n.CacheMisses, n.OldSecret,
+		)
+	}
+	for keyID, at := range lastUsed {
+		batch.Queue(
+			`UPDATE api_keys SET last_used_at = GREATEST(last_used_at, $2) WHERE id = $1`, keyID, at,
+		)
+	}
+
+	if err := u.db.SendBatch(ctx, batch).Close(); err != nil {
+		log.Printf("Error writing API key usage: %v", err)
+		u.restore(counts, lastUsed)
+	}
+}
+
+// restore merges usage that failed to write back into the pending usage
+func (u *UsageRecorder) restore(counts map[usageBucket]*usageCounts, lastUsed map[string]time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for b, n := range counts {
+		if pending := u.counts[b]; pending != nil {
+			pending.Requests += n.Requests
+			pending.Errors += n.Errors
+			pending.CacheHits += n.CacheHits
+			pending.CacheMisses += n.CacheMisses
+			pending.OldSecret += n.OldSecret
+		} else {
+			u.counts[b] = n
+		}
+	}
+	for keyID, at := range lastUsed {
+		if at.After(u.lastUsed[keyID]) {
+			u.lastUsed[keyID] = at
+		}
+	}
+}
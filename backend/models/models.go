@@ -4,25 +4,28 @@ import "time"
 
 // User represents a user account
 type User struct {
-	ID           string    `json:"id"`
-	Email        string    `json:"email"`
-	Username     string    `json:"username"`
-	PasswordHash string    `json:"-"`
-	Name         string    `json:"name"`
-	AvatarURL    string    `json:"avatar_url"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	Email         string    `json:"email"`
+	Username      string    `json:"username"`
+	PasswordHash  string    `json:"-"`
+	Name          string    `json:"name"`
+	AvatarURL     string    `json:"avatar_url"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 // Project represents a translation project
 type Project struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Slug        string    `json:"slug"`
-	Description string    `json:"description"`
-	CreatedBy   *string   `json:"created_by,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Slug          string    `json:"slug"`
+	Description   string    `json:"description"`
+	ICUValidation bool      `json:"icu_validation"`
+	Require2FA    bool      `json:"require_2fa"`
+	CreatedBy     *string   `json:"created_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 // ProjectWithRole includes the user's role in the project
@@ -33,12 +36,14 @@ type ProjectWithRole struct {
 
 // Language represents a language within a project
 type Language struct {
-	ID        string    `json:"id"`
-	ProjectID string    `json:"project_id"`
-	Code      string    `json:"code"`
-	Name      string    `json:"name"`
-	IsDefault bool      `json:"is_default"`
-	CreatedAt time.Time `json:"created_at"`
+	ID               string    `json:"id"`
+	ProjectID        string    `json:"project_id"`
+	Code             string    `json:"code"`
+	Name             string    `json:"name"`
+	IsDefault        bool      `json:"is_default"`
+	Fallbacks        []string  `json:"fallbacks"`         // ordered language codes used to fill missing values
+	PluralCategories []string  `json:"plural_categories"` // CLDR categories required for plural keys
+	CreatedAt        time.Time `json:"created_at"`
 }
 
 // TranslationKey represents a translation key within a project
@@ -47,39 +52,91 @@ type TranslationKey struct {
 	ProjectID   string    `json:"project_id"`
 	Key         string    `json:"key"`
 	Description string    `json:"description"`
+	IsPlural    bool      `json:"is_plural"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 // Translation represents a translated value
 type Translation struct {
-	ID         string    `json:"id"`
-	KeyID      string    `json:"key_id"`
-	LanguageID string    `json:"language_id"`
-	Value      string    `json:"value"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	UpdatedBy  *string   `json:"updated_by,omitempty"`
+	ID          string            `json:"id"`
+	KeyID       string            `json:"key_id"`
+	LanguageID  string            `json:"language_id"`
+	Value       string            `json:"value"`
+	PluralForms map[string]string `json:"plural_forms,omitempty"` // CLDR category -> value
+	UpdatedAt   time.Time         `json:"updated_at"`
+	UpdatedBy   *string           `json:"updated_by,omitempty"`
 }
 
 // APIKey represents an API key for external access
 type APIKey struct {
-	ID         string    `json:"id"`
-	ProjectID  string    `json:"project_id"`
-	Name       string    `json:"name"`
-	KeyHash    string    `json:"-"`
-	KeyPrefix  string    `json:"key_prefix"`
-	Scopes     []string  `json:"scopes"`
-	IsActive   bool      `json:"is_active"`
-	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
-	CreatedAt  time.Time  `json:"created_at"`
+	ID           string     `json:"id"`
+	ProjectID    string     `json:"project_id"`
+	Name         string     `json:"name"`
+	KeyHash      string     `json:"-"`
+	KeyPrefix    string     `json:"key_prefix"`
+	Scopes       []string   `json:"scopes"`
+	EnvID        *string    `json:"env_id,omitempty"` // environment the key is pinned to
+	IsActive     bool       `json:"is_active"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	AllowedCIDRs []string   `json:"allowed_cidrs"` // empty allows every address
+	// PreviousExpiresAt is when the secret replaced by the last rotation stops working
+	PreviousExpiresAt *time.Time `json:"previous_key_expires_at,omitempty"`
+	RotatedAt         *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt        *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// UsageCounts are the request counters of an API key usage bucket
+type UsageCounts struct {
+	Requests          int `json:"requests"`
+	Errors            int `json:"errors"`
+	CacheHits         int `json:"cache_hits"`
+	CacheMisses       int `json:"cache_misses"`
+	OldSecretRequests int `json:"old_secret_requests"` // made with a secret replaced by rotation
+}
+
+// APIKeyUsageHour is an API key's usage in one hour
+type APIKeyUsageHour struct {
+	Hour time.Time `json:"hour"`
+	UsageCounts
+}
+
+// APIKeyExportUsage counts an API key's successful exports of a language in a format
+type APIKeyExportUsage struct {
+	LanguageCode string `json:"language_code"`
+	Format       string `json:"format"`
+	Requests     int    `json:"requests"`
+	CacheHits    int    `json:"cache_hits"`
+	CacheMisses  int    `json:"cache_misses"`
+}
+
+// APIKeyUsage summarizes an API key's requests since From
+type APIKeyUsage struct {
+	KeyID      string              `json:"key_id"`
+	From       time.Time           `json:"from"`
+	LastUsedAt *time.Time          `json:"last_used_at,omitempty"`
+	Totals     UsageCounts         `json:"totals"`
+	Hours      []APIKeyUsageHour   `json:"hours"`   // hours with requests only
+	Exports    []APIKeyExportUsage `json:"exports"` // most requested first
 }
 
 // TranslationEntry is used for the translation grid (key + all language values)
 type TranslationEntry struct {
-	KeyID       string            `json:"key_id"`
-	Key         string            `json:"key"`
-	Description string            `json:"description"`
-	Values      map[string]string `json:"values"` // language_id -> value
+	KeyID       string                       `json:"key_id"`
+	Key         string                       `json:"key"`
+	Description string                       `json:"description"`
+	IsPlural    bool                         `json:"is_plural"`
+	Values      map[string]string            `json:"values"`            // language_id -> value
+	Plurals     map[string]map[string]string `json:"plurals,omitempty"` // language_id -> category -> value
+	// Values of the selected environment that replace the base values
+	Overrides       map[string]string            `json:"overrides,omitempty"`        // language_id -> value
+	PluralOverrides map[string]map[string]string `json:"plural_overrides,omitempty"` // language_id -> category -> value
+	// Review status per language_id; absent cells are untranslated
+	Statuses         map[string]string `json:"statuses"`
+	OverrideStatuses map[string]string `json:"override_statuses,omitempty"`
+	// Languages whose value was pre-filled by machine translation
+	MachineTranslated map[string]bool `json:"machine_translated,omitempty"`
 }
 
 // ProjectStats holds project statistics
@@ -89,6 +146,65 @@ type ProjectStats struct {
 	LanguageProgress map[string]float64 `json:"language_progress"` // language_code -> percentage
 }
 
+// PlaceholderRename is a placeholder that appears under a different name in a translation
+type PlaceholderRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// PlaceholderIssue reports placeholders of a translation that differ from the default language
+type PlaceholderIssue struct {
+	KeyID        string              `json:"key_id"`
+	Key          string              `json:"key"`
+	LanguageID   string              `json:"language_id"`
+	LanguageCode string              `json:"language_code,omitempty"`
+	Category     string              `json:"category,omitempty"` // plural category, for plural keys
+	Missing      []string            `json:"missing,omitempty"`
+	Extra        []string            `json:"extra,omitempty"`
+	Renamed      []PlaceholderRename `json:"renamed,omitempty"`
+}
+
+// GlossaryTerm is a project term with its approved translations
+type GlossaryTerm struct {
+	ID             string            `json:"id"`
+	ProjectID      string            `json:"project_id"`
+	Term           string            `json:"term"` // in the default language
+	Description    string            `json:"description"`
+	CaseSensitive  bool              `json:"case_sensitive"`
+	DoNotTranslate bool              `json:"do_not_translate"`
+	Translations   map[string]string `json:"translations"` // language_id -> approved term
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// GlossaryIssue reports a translation whose source uses a glossary term
+// without the approved translation of that term
+type GlossaryIssue struct {
+	KeyID        string `json:"key_id"`
+	Key          string `json:"key"`
+	LanguageID   string `json:"language_id"`
+	LanguageCode string `json:"language_code,omitempty"`
+	Category     string `json:"category,omitempty"` // plural category, for plural keys
+	TermID       string `json:"term_id"`
+	Term         string `json:"term"`
+	Expected     string `json:"expected"` // approved translation, or the term itself when it must not be translated
+}
+
+// QAReport holds the quality checks of a project's translations
+type QAReport struct {
+	DefaultLanguage string             `json:"default_language"`
+	Placeholders    []PlaceholderIssue `json:"placeholders"`
+	Glossary        []GlossaryIssue    `json:"glossary"`
+}
+
+// SkippedReview is a cell a review action could not be applied to
+type SkippedReview struct {
+	KeyID      string `json:"key_id"`
+	LanguageID string `json:"language_id"`
+	Status     string `json:"status,omitempty"` // current status, when the cell exists
+	Reason     string `json:"reason"`
+}
+
 // ProjectMember represents a user who is a member of a project
 type ProjectMember struct {
 	ID        string    `json:"id"`
@@ -128,3 +244,128 @@ type Environment struct {
 	Description string    `json:"description"`
 	CreatedAt   time.Time `json:"created_at"`
 }
+
+// PromotedValue is an environment override changed by a promotion
+type PromotedValue struct {
+	KeyID        string `json:"key_id"`
+	Key          string `json:"key"`
+	LanguageID   string `json:"language_id"`
+	LanguageCode string `json:"language_code"`
+	Action       string `json:"action"` // "set" copies the source override, "cleared" removes the target override
+	From         string `json:"from,omitempty"`
+	To           string `json:"to,omitempty"`
+}
+
+// EnvironmentPromotion describes the changes a promotion makes to the target environment
+type EnvironmentPromotion struct {
+	SourceEnvID   string          `json:"source_env_id"`
+	TargetEnvID   string          `json:"target_env_id"`
+	DryRun        bool            `json:"dry_run"`
+	KeysAdded     []string        `json:"keys_added"`
+	KeysRemoved   []string        `json:"keys_removed"`              // only with prune
+	KeysNotPruned []string        `json:"keys_not_pruned,omitempty"` // keys prune would remove, without prune
+	ValuesChanged []PromotedValue `json:"values_changed"`
+	Summary       map[string]int  `json:"summary"`
+}
+
+// TranslationRevision is one entry of a translation's change history
+type TranslationRevision struct {
+	ID             string            `json:"id"`
+	KeyID          string            `json:"key_id"`
+	LanguageID     string            `json:"language_id"`
+	LanguageCode   string            `json:"language_code"`
+	EnvID          *string           `json:"env_id,omitempty"` // set for environment overrides
+	Action         string            `json:"action"`           // update, import, revert, promote or machine
+	OldValue       *string           `json:"old_value"`        // nil when the value did not exist
+	NewValue       *string           `json:"new_value"`        // nil when the override was removed
+	OldPluralForms map[string]string `json:"old_plural_forms,omitempty"`
+	NewPluralForms map[string]string `json:"new_plural_forms,omitempty"`
+	RevertedFrom   *string           `json:"reverted_from,omitempty"`
+	ChangedBy      *string           `json:"changed_by,omitempty"`
+	ChangedByName  string            `json:"changed_by_username,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// KeyComment is a discussion thread on a translation key, or a reply to one
+type KeyComment struct {
+	ID            string       `json:"id"`
+	KeyID         string       `json:"key_id"`
+	LanguageID    *string      `json:"language_id,omitempty"` // set when the thread is about one language
+	ParentID      *string      `json:"parent_id,omitempty"`   // thread of a reply
+	Body          string       `json:"body"`
+	CreatedBy     *string      `json:"created_by,omitempty"`
+	CreatedByName string       `json:"created_by_username,omitempty"`
+	ResolvedBy    *string      `json:"resolved_by,omitempty"`
+	ResolvedAt    *time.Time   `json:"resolved_at,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+	Replies       []KeyComment `json:"replies,omitempty"` // threads only, oldest first
+}
+
+// MemorySuggestion is an approved translation of a similar source text
+type MemorySuggestion struct {
+	SourceText string   `json:"source_text"`
+	TargetText string   `json:"target_text"`
+	Score      float64  `json:"score"` // trigram similarity of the source texts, 1 for exact matches
+	Match      string   `json:"match"` // exact or fuzzy
+	Uses       int      `json:"uses"`  // keys translated this way
+	Projects   []string `json:"projects"`
+}
+
+// MemorySuggestions lists translation memory matches for a key
+type MemorySuggestions struct {
+	KeyID          string             `json:"key_id"`
+	SourceLanguage string             `json:"source_language"`
+	TargetLanguage string             `json:"target_language"`
+	Source         string             `json:"source"`
+	Suggestions    []MemorySuggestion `json:"suggestions"`
+}
+
+// MachineTranslationSettings is the machine-translation provider of a project.
+// The API key is never returned.
+type MachineTranslationSettings struct {
+	Provider  string     `json:"provider"` // "" when not configured
+	Endpoint  string     `json:"endpoint"`
+	HasAPIKey bool       `json:"has_api_key"`
+	Providers []string   `json:"providers"` // available providers
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// SkippedTranslation is a cell machine translation left untouched
+type SkippedTranslation struct {
+	KeyID  string `json:"key_id"`
+	Key    string `json:"key"`
+	Reason string `json:"reason"`
+}
+
+// Release is an immutable snapshot of a project's translations
+type Release struct {
+	ID             string    `json:"id"`
+	ProjectID      string    `json:"project_id"`
+	EnvID          *string   `json:"env_id,omitempty"` // environment the snapshot was taken from
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	SourceLanguage string    `json:"source_language"`
+	KeyCount       int       `json:"key_count"`
+	CreatedBy      *string   `json:"created_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ReleaseChange is a value that differs between two releases
+type ReleaseChange struct {
+	Key             string            `json:"key"`
+	LanguageCode    string            `json:"language_code"`
+	Change          string            `json:"change"` // added, removed or changed
+	From            *string           `json:"from"`
+	To              *string           `json:"to"`
+	FromPluralForms map[string]string `json:"from_plural_forms,omitempty"`
+	ToPluralForms   map[string]string `json:"to_plural_forms,omitempty"`
+}
+
+// ReleaseDiff lists the changes between two releases
+type ReleaseDiff struct {
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Changes []ReleaseChange `json:"changes"`
+	Summary map[string]int  `json:"summary"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // RegisterRequest is the request body for user registration
 type RegisterRequest struct {
 	Email    string `json:"email" validate:"required,email"`
@@ -14,10 +16,67 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
-// AuthResponse is returned after successful login/register
+// AuthResponse is returned after successful login/register/refresh. Token is
+// a short-lived access token (ExpiresIn seconds); RefreshToken obtains a new
+// pair from /api/auth/refresh and can be used only once.
 type AuthResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	User         User   `json:"user"`
+}
+
+// MFAChallenge is returned instead of an AuthResponse when the user must
+// complete the login with a second factor
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// VerifyTwoFactorRequest is the request body for the second login step.
+// Code is a TOTP code or a recovery code.
+type VerifyTwoFactorRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// TwoFactorCodeRequest is the request body for confirming, disabling or
+// regenerating recovery codes of two-factor authentication
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorEnrollment is returned when starting two-factor enrollment
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RefreshTokenRequest is the request body for refreshing an access token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// VerifyEmailRequest is the request body for confirming an email address
+type VerifyEmailRequest struct {
 	Token string `json:"token"`
-	User  User   `json:"user"`
+}
+
+// ForgotPasswordRequest is the request body for requesting a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// OIDCTokenRequest is the request body for completing a single sign-on login
+type OIDCTokenRequest struct {
+	Code string `json:"code"`
+}
+
+// ResetPasswordRequest is the request body for setting a new password
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
 }
 
 // CreateProjectRequest is the request body for creating a project
@@ -28,33 +87,44 @@ type CreateProjectRequest struct {
 
 // UpdateProjectRequest is the request body for updating a project
 type UpdateProjectRequest struct {
-	Name        string `json:"name" validate:"required,min=1,max=255"`
-	Description string `json:"description"`
+	Name          string `json:"name" validate:"required,min=1,max=255"`
+	Description   string `json:"description"`
+	ICUValidation *bool  `json:"icu_validation"` // unchanged when omitted
+	Require2FA    *bool  `json:"require_2fa"`    // unchanged when omitted
 }
 
 // CreateLanguageRequest is the request body for adding a language
 type CreateLanguageRequest struct {
-	Code      string `json:"code" validate:"required,min=2,max=10"`
-	Name      string `json:"name" validate:"required,min=1,max=100"`
-	IsDefault bool   `json:"is_default"`
+	Code      string   `json:"code" validate:"required,min=2,max=10"`
+	Name      string   `json:"name" validate:"required,min=1,max=100"`
+	IsDefault bool     `json:"is_default"`
+	Fallbacks []string `json:"fallbacks"`
 }
 
 // UpdateLanguageRequest is the request body for updating a language
 type UpdateLanguageRequest struct {
-	Name      string `json:"name" validate:"required,min=1,max=100"`
-	IsDefault bool   `json:"is_default"`
+	Name      string   `json:"name" validate:"required,min=1,max=100"`
+	IsDefault bool     `json:"is_default"`
+	Fallbacks []string `json:"fallbacks"` // omitted keeps the current chain
 }
 
 // CreateKeyRequest is the request body for creating a translation key
 type CreateKeyRequest struct {
 	Key         string `json:"key" validate:"required,min=1,max=500"`
 	Description string `json:"description"`
+	IsPlural    bool   `json:"is_plural"`
+}
+
+// PushKeysRequest is the request body for pushing keys from CI
+type PushKeysRequest struct {
+	Keys []CreateKeyRequest `json:"keys"`
 }
 
 // UpdateKeyRequest is the request body for updating a translation key
 type UpdateKeyRequest struct {
 	Key         string `json:"key" validate:"required,min=1,max=500"`
 	Description string `json:"description"`
+	IsPlural    *bool  `json:"is_plural"` // unchanged when omitted
 }
 
 // BatchTranslationUpdate represents a batch of translation updates
@@ -62,17 +132,89 @@ type BatchTranslationUpdate struct {
 	Translations []TranslationUpdate `json:"translations" validate:"required"`
 }
 
-// TranslationUpdate represents a single translation value update
+// TranslationUpdate represents a single translation value update.
+// Plural keys send PluralForms (CLDR category -> value) instead of Value.
 type TranslationUpdate struct {
+	KeyID       string            `json:"key_id" validate:"required"`
+	LanguageID  string            `json:"language_id" validate:"required"`
+	Value       string            `json:"value"`
+	PluralForms map[string]string `json:"plural_forms,omitempty"`
+	// With env_id, removes the environment override instead of writing one
+	ResetOverride bool `json:"reset_override,omitempty"`
+}
+
+// ReviewTranslationsRequest moves translation cells through the review workflow
+type ReviewTranslationsRequest struct {
+	Action       string       `json:"action" validate:"required,oneof=submit approve reject"`
+	Translations []ReviewCell `json:"translations" validate:"required"`
+}
+
+// ReviewCell identifies a translation cell to review
+type ReviewCell struct {
 	KeyID      string `json:"key_id" validate:"required"`
 	LanguageID string `json:"language_id" validate:"required"`
-	Value      string `json:"value"`
+}
+
+// CreateCommentRequest is the request body for starting a thread or replying
+type CreateCommentRequest struct {
+	Body       string `json:"body" validate:"required"`
+	LanguageID string `json:"language_id"` // optional, threads only
+}
+
+// UpdateMachineTranslationRequest configures a project's machine-translation
+// provider. An empty provider removes the configuration.
+type UpdateMachineTranslationRequest struct {
+	Provider string  `json:"provider"`
+	Endpoint string  `json:"endpoint"`
+	APIKey   *string `json:"api_key"` // nil keeps the stored key
+}
+
+// MachineTranslateRequest selects the cells to pre-fill by machine translation
+type MachineTranslateRequest struct {
+	LanguageID string   `json:"language_id" validate:"required"`
+	KeyIDs     []string `json:"key_ids"` // optional, defaults to every key
+}
+
+// CreateGlossaryTermRequest is the request body for adding a glossary term
+type CreateGlossaryTermRequest struct {
+	Term           string            `json:"term" validate:"required"`
+	Description    string            `json:"description"`
+	CaseSensitive  bool              `json:"case_sensitive"`
+	DoNotTranslate bool              `json:"do_not_translate"`
+	Translations   map[string]string `json:"translations"` // language_id -> approved term
+}
+
+// UpdateGlossaryTermRequest changes a glossary term. Nil fields are left
+// unchanged; translations are merged per language and an empty value removes one.
+type UpdateGlossaryTermRequest struct {
+	Term           *string           `json:"term"`
+	Description    *string           `json:"description"`
+	CaseSensitive  *bool             `json:"case_sensitive"`
+	DoNotTranslate *bool             `json:"do_not_translate"`
+	Translations   map[string]string `json:"translations"`
 }
 
 // CreateAPIKeyRequest is the request body for generating an API key
 type CreateAPIKeyRequest struct {
-	Name   string   `json:"name" validate:"required,min=1,max=255"`
-	Scopes []string `json:"scopes"`
+	Name         string     `json:"name" validate:"required,min=1,max=255"`
+	Scopes       []string   `json:"scopes"`
+	EnvID        string     `json:"env_id"`        // optional, pins the key to one environment
+	ExpiresAt    *time.Time `json:"expires_at"`    // optional
+	AllowedCIDRs []string   `json:"allowed_cidrs"` // optional, e.g. ["10.0.0.0/8", "203.0.113.7"]
+}
+
+// UpdateAPIKeyRequest is the request body for changing an API key's expiry
+// and IP allowlist. Omitted fields are left as they are.
+type UpdateAPIKeyRequest struct {
+	ExpiresAt    *time.Time `json:"expires_at"`
+	ClearExpiry  bool       `json:"clear_expiry"`  // removes the expiry; ignored with expires_at
+	AllowedCIDRs *[]string  `json:"allowed_cidrs"` // [] allows every address
+}
+
+// RotateAPIKeyRequest is the request body for rotating an API key's secret
+type RotateAPIKeyRequest struct {
+	// OverlapMinutes is how long the old secret keeps working (default 60, 0 ends it at once)
+	OverlapMinutes *int `json:"overlap_minutes"`
 }
 
 // CreateAPIKeyResponse includes the raw key (only shown once)
@@ -81,10 +223,13 @@ type CreateAPIKeyResponse struct {
 	RawKey string `json:"raw_key"`
 }
 
-// ImportRequest for importing translation JSON
+// ImportRequest for importing translations. JSON imports use Translations;
+// file formats (e.g. xliff12, xliff20, po) pass the raw file in Content.
 type ImportRequest struct {
-	LanguageCode string            `json:"language_code" validate:"required"`
-	Translations map[string]interface{} `json:"translations" validate:"required"`
+	LanguageCode string                 `json:"language_code"`
+	Format       string                 `json:"format"`
+	Translations map[string]interface{} `json:"translations"`
+	Content      string                 `json:"content"`
 }
 
 // CreateEnvironmentRequest is the request body for creating an environment
@@ -100,6 +245,32 @@ type UpdateEnvironmentRequest struct {
 	Description string `json:"description"`
 }
 
+// PromoteEnvironmentRequest is the request body for promoting an environment into another
+type PromoteEnvironmentRequest struct {
+	TargetEnvID string `json:"target_env_id" validate:"required"`
+	DryRun      bool   `json:"dry_run"` // only compute the diff
+	Prune       bool   `json:"prune"`   // also remove keys missing from the source
+}
+
+// CreateReleaseRequest is the request body for freezing a release
+type CreateReleaseRequest struct {
+	Name        string `json:"name" validate:"required,min=1,max=100"`
+	Description string `json:"description"`
+	EnvID       string `json:"env_id"` // optional, snapshot one environment
+	// Freeze the last approved values instead of the latest ones
+	ApprovedOnly bool `json:"approved_only"`
+}
+
+// TranslationValidationError reports a rejected translation cell
+type TranslationValidationError struct {
+	KeyID      string `json:"key_id"`
+	Key        string `json:"key,omitempty"`
+	LanguageID string `json:"language_id"`
+	Category   string `json:"category,omitempty"` // plural category, for plural keys
+	Offset     int    `json:"offset"`             // character offset of the error
+	Message    string `json:"message"`
+}
+
 // ErrorResponse is a standard error response
 type ErrorResponse struct {
 	Error   string `json:"error"`
@@ -1,10 +1,14 @@
 package routes
 
 import (
+	"time"
+
 	"translate-management/cache"
 	"translate-management/config"
 	"translate-management/handlers"
+	"translate-management/mailer"
 	"translate-management/middleware"
+	"translate-management/oidc"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -12,7 +16,7 @@ import (
 
 func Setup(app *fiber.App, db *pgxpool.Pool, rdb *cache.RedisClient, cfg *config.Config) {
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db, cfg)
+	authHandler := handlers.NewAuthHandler(db, rdb, mailer.New(cfg), oidc.New(cfg), cfg)
 	projectHandler := handlers.NewProjectHandler(db)
 	languageHandler := handlers.NewLanguageHandler(db, rdb)
 	keyHandler := handlers.NewKeyHandler(db, rdb)
@@ -23,27 +27,84 @@ func Setup(app *fiber.App, db *pgxpool.Pool, rdb *cache.RedisClient, cfg *config
 	importHandler := handlers.NewImportHandler(db)
 	projectExportHandler := handlers.NewProjectExportHandler(db)
 	invitationHandler := handlers.NewInvitationHandler(db)
-	environmentHandler := handlers.NewEnvironmentHandler(db)
+	environmentHandler := handlers.NewEnvironmentHandler(db, rdb)
+	qaHandler := handlers.NewQAHandler(db)
+	historyHandler := handlers.NewHistoryHandler(db, rdb)
+	releaseHandler := handlers.NewReleaseHandler(db)
+	reviewHandler := handlers.NewReviewHandler(db, rdb)
+	commentHandler := handlers.NewCommentHandler(db)
+	memoryHandler := handlers.NewMemoryHandler(db)
+	machineHandler := handlers.NewMachineTranslationHandler(db, rdb)
+	glossaryHandler := handlers.NewGlossaryHandler(db)
+	ciHandler := handlers.NewCIHandler(db, rdb)
+
+	// API key usage is written in batches, and once more on shutdown
+	usage := middleware.NewUsageRecorder(db)
+	usage.Start(10 * time.Second)
+	app.Hooks().OnShutdown(func() error {
+		usage.Stop()
+		return nil
+	})
 
 	api := app.Group("/api")
 	// Export routes (API key auth)
-	export := api.Group("/export", middleware.APIKeyAuth(db))
+	export := api.Group("/export", middleware.APIKeyAuth(db, usage), middleware.RequireScope("read"))
 	export.Get("/:slug/:langCode", exportHandler.Export)
 	export.Get("/:slug/:langCode/version", exportHandler.GetVersion)
 
+	// CI routes (API key auth)
+	ci := api.Group("/v1/ci", middleware.APIKeyAuth(db, usage))
+	ci.Post("/:slug/keys", middleware.RequireScope("write"), ciHandler.PushKeys)
+	ci.Post("/:slug/source", middleware.RequireScope("write"), ciHandler.UploadSource)
+	ci.Post("/:slug/import", middleware.RequireScope("import"), ciHandler.Import)
+
 	// Auth routes (public)
+	// Logins lock out a username after 5 failures and an IP after 20, for
+	// a minute that doubles with each further failure up to an hour
+	loginLimits := []fiber.Handler{
+		middleware.RateLimit(rdb, middleware.RateLimitConfig{Name: "login", Max: 30, Window: time.Minute}),
+		middleware.Backoff(rdb, middleware.BackoffConfig{Name: "login-user", Key: middleware.BodyField("username"),
+			Threshold: 5, BaseLock: time.Minute, MaxLock: time.Hour, ResetOnSuccess: true}),
+		middleware.Backoff(rdb, middleware.BackoffConfig{Name: "login-ip",
+			Threshold: 20, BaseLock: time.Minute, MaxLock: time.Hour}),
+	}
+	registerLimits := []fiber.Handler{
+		middleware.RateLimit(rdb, middleware.RateLimitConfig{Name: "register", Max: 10, Window: time.Hour}),
+		middleware.RateLimit(rdb, middleware.RateLimitConfig{Name: "register-user", Key: middleware.BodyField("username"),
+			Max: 5, Window: time.Hour}),
+	}
 	auth := api.Group("/auth")
-	auth.Post("/register", authHandler.Register)
-	auth.Post("/login", authHandler.Login)
+	auth.Post("/register", append(registerLimits, authHandler.Register)...)
+	auth.Post("/login", append(loginLimits, authHandler.Login)...)
+	auth.Post("/refresh", authHandler.Refresh)
+	auth.Post("/verify-email", authHandler.VerifyEmail)
+	auth.Post("/forgot-password",
+		middleware.RateLimit(rdb, middleware.RateLimitConfig{Name: "forgot-password", Max: 5, Window: 15 * time.Minute}),
+		middleware.RateLimit(rdb, middleware.RateLimitConfig{Name: "forgot-password-email", Key: middleware.BodyField("email"),
+			Max: 3, Window: time.Hour}),
+		authHandler.ForgotPassword)
+	auth.Post("/reset-password", authHandler.ResetPassword)
+	auth.Get("/methods", authHandler.Methods)
+	auth.Get("/oidc/login", authHandler.OIDCLogin)
+	auth.Get("/oidc/callback", authHandler.OIDCCallback)
+	auth.Post("/oidc/token", authHandler.OIDCToken)
+	auth.Post("/2fa/verify", middleware.Backoff(rdb, middleware.BackoffConfig{Name: "2fa-ip",
+		Threshold: 20, BaseLock: time.Minute, MaxLock: time.Hour}), authHandler.VerifyTwoFactor)
 
 	// Auth routes (protected)
-	auth.Post("/logout", middleware.AuthRequired(cfg), authHandler.Logout)
-	auth.Get("/me", middleware.AuthRequired(cfg), authHandler.Me)
+	auth.Post("/logout", middleware.AuthRequired(cfg, rdb), authHandler.Logout)
+	auth.Get("/me", middleware.AuthRequired(cfg, rdb), authHandler.Me)
+	auth.Post("/verify-email/resend", middleware.AuthRequired(cfg, rdb), authHandler.ResendVerification)
+	auth.Get("/2fa", middleware.AuthRequired(cfg, rdb), authHandler.TwoFactorStatus)
+	auth.Post("/2fa/enroll", middleware.AuthRequired(cfg, rdb), authHandler.EnrollTwoFactor)
+	auth.Post("/2fa/confirm", middleware.AuthRequired(cfg, rdb), authHandler.ConfirmTwoFactor)
+	auth.Post("/2fa/recovery-codes", middleware.AuthRequired(cfg, rdb), authHandler.RegenerateRecoveryCodes)
+	auth.Post("/2fa/disable", middleware.AuthRequired(cfg, rdb), authHandler.DisableTwoFactor)
 
 
     // Protected routes
 	// Projects
-	projects := api.Group("/projects",middleware.AuthRequired(cfg))
+	projects := api.Group("/projects",middleware.AuthRequired(cfg, rdb), middleware.ProjectMFARequired(db))
 	projects.Get("/", projectHandler.List)
 	projects.Post("/", projectHandler.Create)
 	projects.Get("/:id", projectHandler.Get)
@@ -63,10 +124,37 @@ func Setup(app *fiber.App, db *pgxpool.Pool, rdb *cache.RedisClient, cfg *config
 	projects.Post("/:id/keys", keyHandler.Create)
 	projects.Put("/:id/keys/:keyId", keyHandler.Update)
 	projects.Delete("/:id/keys/:keyId", keyHandler.Delete)
+	projects.Get("/:id/keys/:keyId/history", historyHandler.List)
+	projects.Post("/:id/keys/:keyId/history/:revisionId/revert", historyHandler.Revert)
+	projects.Get("/:id/keys/:keyId/suggestions", memoryHandler.Suggestions)
+	projects.Get("/:id/keys/:keyId/comments", commentHandler.List)
+	projects.Post("/:id/keys/:keyId/comments", commentHandler.Create)
+	projects.Post("/:id/keys/:keyId/comments/:commentId/replies", commentHandler.Reply)
+	projects.Post("/:id/keys/:keyId/comments/:commentId/resolve", commentHandler.Resolve)
+	projects.Post("/:id/keys/:keyId/comments/:commentId/reopen", commentHandler.Reopen)
+	projects.Delete("/:id/keys/:keyId/comments/:commentId", commentHandler.Delete)
 
 	// Translations
 	projects.Get("/:id/translations", translationHandler.Get)
 	projects.Put("/:id/translations", translationHandler.BatchUpdate)
+	projects.Post("/:id/translations/review", reviewHandler.Review)
+	projects.Post("/:id/translations/machine", machineHandler.Translate)
+	projects.Get("/:id/machine-translation", machineHandler.GetSettings)
+	projects.Put("/:id/machine-translation", machineHandler.UpdateSettings)
+
+	// Releases
+	projects.Get("/:id/releases", releaseHandler.List)
+	projects.Post("/:id/releases", releaseHandler.Create)
+	projects.Get("/:id/releases/diff", releaseHandler.Diff)
+
+	// Quality checks
+	projects.Get("/:id/qa", qaHandler.Report)
+
+	// Glossary
+	projects.Get("/:id/glossary", glossaryHandler.List)
+	projects.Post("/:id/glossary", glossaryHandler.Create)
+	projects.Put("/:id/glossary/:termId", glossaryHandler.Update)
+	projects.Delete("/:id/glossary/:termId", glossaryHandler.Delete)
 
 	// Import
 	projects.Post("/:id/import", importHandler.Import)
@@ -77,7 +165,10 @@ func Setup(app *fiber.App, db *pgxpool.Pool, rdb *cache.RedisClient, cfg *config
 	// API keys
 	projects.Get("/:id/api-keys", apiKeyHandler.List)
 	projects.Post("/:id/api-keys", apiKeyHandler.Create)
+	projects.Put("/:id/api-keys/:keyId", apiKeyHandler.Update)
 	projects.Delete("/:id/api-keys/:keyId", apiKeyHandler.Delete)
+	projects.Post("/:id/api-keys/:keyId/rotate", apiKeyHandler.Rotate)
+	projects.Get("/:id/api-keys/:keyId/usage", apiKeyHandler.Usage)
 
 	// Cache management
 	projects.Post("/:id/cache/invalidate", cacheHandler.Invalidate)
@@ -86,12 +177,13 @@ func Setup(app *fiber.App, db *pgxpool.Pool, rdb *cache.RedisClient, cfg *config
 
 	// Invitations
 	projects.Post("/:id/invitations", invitationHandler.InviteUser)
-	api.Get("/invitations", middleware.AuthRequired(cfg), invitationHandler.GetInvitations)
-	api.Post("/invitations/:id/respond", middleware.AuthRequired(cfg), invitationHandler.RespondToInvitation)
+	api.Get("/invitations", middleware.AuthRequired(cfg, rdb), invitationHandler.GetInvitations)
+	api.Post("/invitations/:id/respond", middleware.AuthRequired(cfg, rdb), invitationHandler.RespondToInvitation)
 
 	// Environments
 	projects.Get("/:id/environments", environmentHandler.List)
 	projects.Post("/:id/environments", environmentHandler.Create)
 	projects.Put("/:id/environments/:envId", environmentHandler.Update)
 	projects.Delete("/:id/environments/:envId", environmentHandler.Delete)
+	projects.Post("/:id/environments/:envId/promote", environmentHandler.Promote)
 }
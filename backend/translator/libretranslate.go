@@ -0,0 +1,118 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("libretranslate", NewLibreTranslate)
+}
+
+// maxLibreResponse bounds the response body read from the endpoint
+const maxLibreResponse = 10 << 20
+
+// LibreTranslate talks to a LibreTranslate-compatible HTTP endpoint
+type LibreTranslate struct {
+	Endpoint string
+	APIKey   string
+	Client   *http.Client
+}
+
+// NewLibreTranslate creates a LibreTranslate client. The endpoint is the base
+// URL of the server, e.g. "http://localhost:5000".
+func NewLibreTranslate(cfg Config) (MachineTranslator, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("Endpoint is required for LibreTranslate")
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("Endpoint must be an http or https URL")
+	}
+	return &LibreTranslate{
+		Endpoint: strings.TrimRight(cfg.Endpoint, "/"),
+		APIKey:   cfg.APIKey,
+		Client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+type libreRequest struct {
+	Q      []string `json:"q"`
+	Source string   `json:"source"`
+	Target string   `json:"target"`
+	Format string   `json:"format"`
+	APIKey string   `json:"api_key,omitempty"`
+}
+
+type libreResponse struct {
+	TranslatedText []string `json:"translatedText"`
+	Error          string   `json:"error"`
+}
+
+// Translate sends the texts in a single /translate request
+func (l *LibreTranslate) Translate(ctx context.Context, texts []string, source, target string) ([]string, error) {
+	if len(texts) == 0 {
+		return []string{}, nil
+	}
+
+	body, err := json.Marshal(libreRequest{
+		Q:      texts,
+		Source: libreLanguage(source),
+		Target: libreLanguage(target),
+		Format: "text",
+		APIKey: l.APIKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.Endpoint+"/translate", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out libreResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxLibreResponse)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("libretranslate: invalid response (HTTP %d)", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if out.Error == "" {
+			out.Error = resp.Status
+		}
+		return nil, fmt.Errorf("libretranslate: %s", out.Error)
+	}
+	if len(out.TranslatedText) != len(texts) {
+		return nil, fmt.Errorf("libretranslate: got %d translations for %d texts", len(out.TranslatedText), len(texts))
+	}
+	return out.TranslatedText, nil
+}
+
+// libreLanguage maps a project language code to LibreTranslate's codes, which
+// are bare languages except for a few scripts (e.g. "pt-BR" -> "pt",
+// "zh-Hant" -> "zt")
+func libreLanguage(code string) string {
+	lower := strings.ToLower(strings.ReplaceAll(code, "_", "-"))
+	switch lower {
+	case "zh-hant", "zh-tw", "zh-hk":
+		return "zt"
+	}
+	if i := strings.Index(lower, "-"); i > 0 {
+		return lower[:i]
+	}
+	return lower
+}
@@ -0,0 +1,67 @@
+// Package translator provides machine-translation providers behind a common
+// interface. Providers register a factory under a name; projects select one by
+// name along with its endpoint and credentials.
+package translator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MachineTranslator translates batches of plain-text messages
+type MachineTranslator interface {
+	// Translate returns one translation per text, in order. Language codes
+	// are the project's language codes (e.g. "en", "pt-BR").
+	Translate(ctx context.Context, texts []string, source, target string) ([]string, error)
+}
+
+// Config is the per-project configuration of a provider
+type Config struct {
+	Endpoint string
+	APIKey   string
+}
+
+// Factory creates a provider from its configuration
+type Factory func(cfg Config) (MachineTranslator, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a provider available under name. It panics if the name is
+// already taken.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := factories[name]; dup {
+		panic("translator: provider registered twice: " + name)
+	}
+	factories[name] = factory
+}
+
+// New creates the provider registered under name
+func New(name string, cfg Config) (MachineTranslator, error) {
+	mu.RLock()
+	factory, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Provider must be one of: %s", strings.Join(Providers(), ", "))
+	}
+	return factory(cfg)
+}
+
+// Providers returns the registered provider names in sorted order
+func Providers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,6 +1,6 @@
 import { browser } from "$app/environment";
 
-const API_URL = browser
+export const API_URL = browser
   ? import.meta.env.PUBLIC_API_URL || "http://localhost:3000"
   : "http://backend:3000";
 
@@ -13,6 +13,7 @@ interface RequestOptions {
 
 class ApiClient {
   private baseUrl: string;
+  private refreshing: Promise<boolean> | null = null;
 
   constructor(baseUrl: string) {
     this.baseUrl = baseUrl;
@@ -23,7 +24,41 @@ class ApiClient {
     return localStorage.getItem("token");
   }
 
-  async request<T>(endpoint: string, options: RequestOptions = {}): Promise<T> {
+  // Exchanges the stored refresh token for a new token pair. Concurrent
+  // callers share one request, since a refresh token can be used only once.
+  private refreshToken(): Promise<boolean> {
+    if (!browser) return Promise.resolve(false);
+    const refreshToken = localStorage.getItem("refresh_token");
+    if (!refreshToken) return Promise.resolve(false);
+
+    this.refreshing ??= fetch(`${this.baseUrl}/api/auth/refresh`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    })
+      .then(async (response) => {
+        if (!response.ok) {
+          localStorage.removeItem("token");
+          localStorage.removeItem("refresh_token");
+          return false;
+        }
+        const res = await response.json();
+        localStorage.setItem("token", res.token);
+        localStorage.setItem("refresh_token", res.refresh_token);
+        return true;
+      })
+      .catch(() => false)
+      .finally(() => {
+        this.refreshing = null;
+      });
+    return this.refreshing;
+  }
+
+  async request<T>(
+    endpoint: string,
+    options: RequestOptions = {},
+    retry = true,
+  ): Promise<T> {
     const {
       method = "GET",
       body,
@@ -47,6 +82,18 @@ class ApiClient {
 
     const response = await fetch(`${this.baseUrl}${endpoint}`, config);
 
+    // Access tokens are short-lived: refresh once and retry
+    if (
+      response.status === 401 &&
+      token &&
+      retry &&
+      endpoint !== "/api/auth/login" &&
+      endpoint !== "/api/auth/register" &&
+      (await this.refreshToken())
+    ) {
+      return this.request<T>(endpoint, options, false);
+    }
+
     if (!response.ok) {
       const error = await response
         .json()
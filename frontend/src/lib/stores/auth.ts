@@ -2,7 +2,7 @@ import { writable } from "svelte/store";
 import { browser } from "$app/environment";
 import { goto } from "$app/navigation";
 import { api } from "$lib/api/client";
-import type { User, AuthResponse } from "$lib/types";
+import type { User, AuthResponse, MFAChallenge } from "$lib/types";
 
 function createAuthStore() {
   const storedToken = browser ? localStorage.getItem("token") : null;
@@ -18,21 +18,33 @@ function createAuthStore() {
     loading: false,
   });
 
+  // Stores the session of a completed login, or returns the mfa token when
+  // the login still needs a second factor
+  function finishLogin(res: AuthResponse | MFAChallenge): string | null {
+    if ("mfa_required" in res) {
+      update((s) => ({ ...s, loading: false }));
+      return res.mfa_token;
+    }
+    if (browser) {
+      localStorage.setItem("token", res.token);
+      localStorage.setItem("refresh_token", res.refresh_token);
+      localStorage.setItem("user", JSON.stringify(res.user));
+    }
+    set({ user: res.user, token: res.token, loading: false });
+    goto("/");
+    return null;
+  }
+
   return {
     subscribe,
     login: async (username: string, password: string) => {
       update((s) => ({ ...s, loading: true }));
       try {
-        const res = await api.post<AuthResponse>("/api/auth/login", {
-          username,
-          password,
-        });
-        if (browser) {
-          localStorage.setItem("token", res.token);
-          localStorage.setItem("user", JSON.stringify(res.user));
-        }
-        set({ user: res.user, token: res.token, loading: false });
-        goto("/");
+        const res = await api.post<AuthResponse | MFAChallenge>(
+          "/api/auth/login",
+          { username, password },
+        );
+        return finishLogin(res);
       } catch (err) {
         update((s) => ({ ...s, loading: false }));
         throw err;
@@ -54,6 +66,7 @@ function createAuthStore() {
         });
         if (browser) {
           localStorage.setItem("token", res.token);
+          localStorage.setItem("refresh_token", res.refresh_token);
           localStorage.setItem("user", JSON.stringify(res.user));
         }
         set({ user: res.user, token: res.token, loading: false });
@@ -63,8 +76,40 @@ function createAuthStore() {
         throw err;
       }
     },
+    // Completes a single sign-on login with the one-time code from the
+    // backend's redirect
+    loginWithSSO: async (code: string) => {
+      update((s) => ({ ...s, loading: true }));
+      try {
+        const res = await api.post<AuthResponse | MFAChallenge>(
+          "/api/auth/oidc/token",
+          { code },
+        );
+        return finishLogin(res);
+      } catch (err) {
+        update((s) => ({ ...s, loading: false }));
+        throw err;
+      }
+    },
+    // Second login step for users with two-factor authentication
+    verifyTwoFactor: async (mfaToken: string, code: string) => {
+      update((s) => ({ ...s, loading: true }));
+      try {
+        const res = await api.post<AuthResponse>("/api/auth/2fa/verify", {
+          mfa_token: mfaToken,
+          code,
+        });
+        finishLogin(res);
+      } catch (err) {
+        update((s) => ({ ...s, loading: false }));
+        throw err;
+      }
+    },
     logout: () => {
       if (browser) {
+        // Revoke the session; the token is read before storage is cleared
+        api.post("/api/auth/logout").catch(() => {});
+
         // Clear all storage
         localStorage.clear();
         sessionStorage.clear();
@@ -90,6 +135,7 @@ function createAuthStore() {
       } catch {
         if (browser) {
           localStorage.removeItem("token");
+          localStorage.removeItem("refresh_token");
           localStorage.removeItem("user");
         }
         set({ user: null, token: null, loading: false });
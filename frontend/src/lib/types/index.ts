@@ -4,20 +4,30 @@ export interface User {
   username: string;
   name: string;
   avatar_url: string;
+  email_verified: boolean;
   created_at: string;
   updated_at: string;
 }
 
 export interface AuthResponse {
   token: string;
+  refresh_token: string;
+  expires_in: number;
   user: User;
 }
 
+export interface MFAChallenge {
+  mfa_required: true;
+  mfa_token: string;
+  expires_in: number;
+}
+
 export interface Project {
   id: string;
   name: string;
   slug: string;
   description: string;
+  require_2fa?: boolean;
   created_by?: string;
   created_at: string;
   updated_at: string;
@@ -62,10 +72,31 @@ export interface APIKey {
   key_prefix: string;
   scopes: string[];
   is_active: boolean;
+  expires_at?: string;
+  allowed_cidrs: string[];
+  previous_key_expires_at?: string;
+  rotated_at?: string;
   last_used_at?: string;
   created_at: string;
 }
 
+export interface UsageCounts {
+  requests: number;
+  errors: number;
+  cache_hits: number;
+  cache_misses: number;
+  old_secret_requests: number;
+}
+
+export interface APIKeyUsage {
+  key_id: string;
+  from: string;
+  last_used_at?: string;
+  totals: UsageCounts;
+  hours: (UsageCounts & { hour: string })[];
+  exports: { language_code: string; format: string; requests: number; cache_hits: number; cache_misses: number }[];
+}
+
 export interface CreateAPIKeyResponse {
   api_key: APIKey;
   raw_key: string;
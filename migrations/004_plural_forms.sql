@@ -0,0 +1,6 @@
+-- Plural-aware translation keys
+ALTER TABLE translation_keys ADD COLUMN is_plural BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- One value per CLDR plural category, e.g. {"one": "1 item", "other": "{count} items"}.
+-- For plural keys translations.value mirrors the "other" form.
+ALTER TABLE translations ADD COLUMN plural_forms JSONB;
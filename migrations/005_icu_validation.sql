@@ -0,0 +1,2 @@
+-- Validate translation values as ICU MessageFormat on save (per project)
+ALTER TABLE projects ADD COLUMN icu_validation BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,3 @@
+-- Ordered fallback chain of language codes, e.g. pt-BR -> {pt, en}.
+-- Exports with ?fallback=true fill untranslated values from these languages.
+ALTER TABLE languages ADD COLUMN fallbacks TEXT[] NOT NULL DEFAULT '{}';
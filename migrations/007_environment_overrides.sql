@@ -0,0 +1,15 @@
+-- Per-environment translation values. An override replaces the base value of
+-- a key/language pair when exporting that environment.
+CREATE TABLE translation_overrides (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    key_id UUID NOT NULL REFERENCES translation_keys(id) ON DELETE CASCADE,
+    language_id UUID NOT NULL REFERENCES languages(id) ON DELETE CASCADE,
+    env_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    value TEXT NOT NULL DEFAULT '',
+    plural_forms JSONB,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    UNIQUE(key_id, language_id, env_id)
+);
+
+CREATE INDEX idx_translation_overrides_env_id ON translation_overrides(env_id);
@@ -0,0 +1,2 @@
+-- Pin an API key to one environment; NULL keys can read every environment
+ALTER TABLE api_keys ADD COLUMN env_id UUID REFERENCES environments(id) ON DELETE CASCADE;
@@ -0,0 +1,19 @@
+-- Append-only history of translation values. env_id is set for changes to
+-- environment overrides; a NULL old/new value means the cell did not exist
+-- (or the override was removed).
+CREATE TABLE translation_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    key_id UUID NOT NULL REFERENCES translation_keys(id) ON DELETE CASCADE,
+    language_id UUID NOT NULL REFERENCES languages(id) ON DELETE CASCADE,
+    env_id UUID REFERENCES environments(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    old_plural_forms JSONB,
+    new_plural_forms JSONB,
+    reverted_from UUID REFERENCES translation_history(id) ON DELETE SET NULL,
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_translation_history_key_id ON translation_history(key_id, created_at DESC);
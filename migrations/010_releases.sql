@@ -0,0 +1,26 @@
+-- Immutable, named snapshots of a project's translations. Entries store
+-- language codes and key names so a release survives later deletions.
+CREATE TABLE releases (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    env_id UUID REFERENCES environments(id) ON DELETE SET NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT DEFAULT '',
+    source_language VARCHAR(10) NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(project_id, name)
+);
+
+CREATE TABLE release_entries (
+    release_id UUID NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+    language_code VARCHAR(10) NOT NULL,
+    key VARCHAR(500) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    is_plural BOOLEAN NOT NULL DEFAULT FALSE,
+    value TEXT NOT NULL DEFAULT '',
+    plural_forms JSONB,
+    PRIMARY KEY (release_id, language_code, key)
+);
+
+CREATE INDEX idx_releases_project_id ON releases(project_id);
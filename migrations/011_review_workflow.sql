@@ -0,0 +1,22 @@
+-- Review workflow: untranslated -> draft -> in_review -> approved / rejected.
+-- approved_value keeps the last approved text so exports in approved_only
+-- mode never serve unreviewed edits. Project members may now also have the
+-- 'reviewer' role.
+ALTER TABLE translations ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'draft';
+ALTER TABLE translations ADD COLUMN approved_value TEXT;
+ALTER TABLE translations ADD COLUMN approved_plural_forms JSONB;
+ALTER TABLE translations ADD COLUMN reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE translations ADD COLUMN reviewed_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE translation_overrides ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'draft';
+ALTER TABLE translation_overrides ADD COLUMN approved_value TEXT;
+ALTER TABLE translation_overrides ADD COLUMN approved_plural_forms JSONB;
+ALTER TABLE translation_overrides ADD COLUMN reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE translation_overrides ADD COLUMN reviewed_at TIMESTAMP WITH TIME ZONE;
+
+-- Values that were live before the workflow existed count as approved
+UPDATE translations SET
+    status = CASE WHEN value = '' THEN 'untranslated' ELSE 'approved' END,
+    approved_value = CASE WHEN value = '' THEN NULL ELSE value END,
+    approved_plural_forms = CASE WHEN value = '' THEN NULL ELSE plural_forms END;
+UPDATE translation_overrides SET status = 'approved', approved_value = value, approved_plural_forms = plural_forms;
@@ -0,0 +1,18 @@
+-- Discussion threads on translation keys. A thread is a comment without a
+-- parent_id; replies point at the thread. language_id optionally ties a
+-- thread to one language.
+CREATE TABLE key_comments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    key_id UUID NOT NULL REFERENCES translation_keys(id) ON DELETE CASCADE,
+    language_id UUID REFERENCES languages(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES key_comments(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_key_comments_key_id ON key_comments(key_id, created_at);
+CREATE INDEX idx_key_comments_parent_id ON key_comments(parent_id);
@@ -0,0 +1,30 @@
+-- Translation memory: approved (default-language value, target value) pairs
+-- across all projects, searched by trigram similarity of the source text.
+-- One entry per key and target language, refreshed on every approval.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE translation_memory (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key_id UUID NOT NULL REFERENCES translation_keys(id) ON DELETE CASCADE,
+    source_language VARCHAR(10) NOT NULL,
+    target_language VARCHAR(10) NOT NULL,
+    source_text TEXT NOT NULL,
+    target_text TEXT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(key_id, target_language)
+);
+
+CREATE INDEX idx_translation_memory_languages ON translation_memory(source_language, target_language);
+CREATE INDEX idx_translation_memory_source_trgm ON translation_memory USING gin (source_text gin_trgm_ops);
+
+-- Index the translations approved so far
+INSERT INTO translation_memory (project_id, key_id, source_language, target_language, source_text, target_text)
+SELECT tk.project_id, tk.id, sl.code, l.code, s.value, t.approved_value
+FROM translations t
+JOIN translation_keys tk ON tk.id = t.key_id
+JOIN languages l ON l.id = t.language_id AND NOT l.is_default
+JOIN languages sl ON sl.project_id = tk.project_id AND sl.is_default
+JOIN translations s ON s.key_id = t.key_id AND s.language_id = sl.id
+WHERE t.approved_value <> '' AND s.value <> ''
+ON CONFLICT (key_id, target_language) DO NOTHING;
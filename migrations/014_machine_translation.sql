@@ -0,0 +1,14 @@
+-- Machine-translation provider of a project (see backend/translator) and a
+-- flag on values pre-filled by it. The flag is cleared when a person edits
+-- the value.
+CREATE TABLE machine_translation_settings (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    endpoint TEXT NOT NULL DEFAULT '',
+    api_key TEXT NOT NULL DEFAULT '',
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+ALTER TABLE translations ADD COLUMN machine_translated BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE translation_overrides ADD COLUMN machine_translated BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,22 @@
+-- Project glossary. Terms are written in the default language; each language
+-- can have an approved translation of the term. do_not_translate terms must
+-- appear unchanged in every translation.
+CREATE TABLE glossary_terms (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    term TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    case_sensitive BOOLEAN NOT NULL DEFAULT FALSE,
+    do_not_translate BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(project_id, term)
+);
+
+CREATE TABLE glossary_translations (
+    term_id UUID NOT NULL REFERENCES glossary_terms(id) ON DELETE CASCADE,
+    language_id UUID NOT NULL REFERENCES languages(id) ON DELETE CASCADE,
+    translation TEXT NOT NULL,
+    PRIMARY KEY (term_id, language_id)
+);
@@ -0,0 +1,15 @@
+-- Accounts at an OpenID Connect provider linked to users. Users created by
+-- single sign-on have an empty password_hash, so they can't log in with a
+-- password until they set one.
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_login_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(issuer, subject)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
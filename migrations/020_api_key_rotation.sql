@@ -0,0 +1,10 @@
+-- API key expiry, rotation and IP allowlists. A rotated key keeps accepting
+-- its previous secret until previous_key_expires_at, so clients can switch
+-- over without downtime. An empty allowlist accepts every address.
+ALTER TABLE api_keys ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE api_keys ADD COLUMN allowed_cidrs TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE api_keys ADD COLUMN previous_key_hash VARCHAR(255);
+ALTER TABLE api_keys ADD COLUMN previous_key_expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE api_keys ADD COLUMN rotated_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_api_keys_previous_key_hash ON api_keys(previous_key_hash);
@@ -0,0 +1,15 @@
+-- Hourly API key usage. Exports are counted per language and format; other
+-- requests (CI writes, refused calls) have empty language_code and format.
+-- old_secret_requests counts calls made with a secret replaced by rotation.
+CREATE TABLE api_key_usage (
+    api_key_id UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    hour TIMESTAMP WITH TIME ZONE NOT NULL,
+    language_code VARCHAR(10) NOT NULL DEFAULT '',
+    format VARCHAR(20) NOT NULL DEFAULT '',
+    requests INTEGER NOT NULL DEFAULT 0,
+    errors INTEGER NOT NULL DEFAULT 0,
+    cache_hits INTEGER NOT NULL DEFAULT 0,
+    cache_misses INTEGER NOT NULL DEFAULT 0,
+    old_secret_requests INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (api_key_id, hour, language_code, format)
+);
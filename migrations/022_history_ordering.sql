@@ -0,0 +1,9 @@
+-- Order history entries written in the same transaction. NOW() is the
+-- transaction start, so a batch update or import gave all its entries one
+-- timestamp; clock_timestamp() advances within a transaction, and seq breaks
+-- any remaining ties in insertion order.
+ALTER TABLE translation_history ALTER COLUMN created_at SET DEFAULT clock_timestamp();
+ALTER TABLE translation_history ADD COLUMN seq BIGSERIAL;
+
+DROP INDEX idx_translation_history_key_id;
+CREATE INDEX idx_translation_history_key_id ON translation_history(key_id, created_at DESC, seq DESC);
@@ -0,0 +1,16 @@
+-- Releases keep each language's fallback chain from when they were taken,
+-- so ?release=...&fallback=true keeps rendering the same strings after the
+-- language settings change
+CREATE TABLE release_languages (
+    release_id UUID NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+    language_code VARCHAR(10) NOT NULL,
+    fallbacks TEXT[] NOT NULL DEFAULT '{}',
+    PRIMARY KEY (release_id, language_code)
+);
+
+-- Releases taken before this migration keep the chains they have today
+INSERT INTO release_languages (release_id, language_code, fallbacks)
+SELECT r.id, l.code, l.fallbacks
+FROM releases r
+JOIN languages l ON l.project_id = r.project_id
+WHERE EXISTS (SELECT 1 FROM release_entries e WHERE e.release_id = r.id AND e.language_code = l.code);